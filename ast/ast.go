@@ -12,6 +12,11 @@ type Node interface {
 	String() string
 	Line() int
 	Column() int
+
+	// Pos returns the position of the first character of the node, End the
+	// position just past its last character.
+	Pos() token.Position
+	End() token.Position
 }
 
 type Statement interface {
@@ -57,20 +62,45 @@ func (program *Program) Column() int {
 	}
 	return program.Statements[0].Column()
 }
+func (program *Program) Pos() token.Position {
+	if len(program.Statements) == 0 {
+		return token.Position{Line: 1, Column: 1}
+	}
+	return program.Statements[0].Pos()
+}
+func (program *Program) End() token.Position {
+	if len(program.Statements) == 0 {
+		return token.Position{Line: 1, Column: 1}
+	}
+	return program.Statements[len(program.Statements)-1].End()
+}
 
 type LetStatement struct {
 	Token      token.Token
 	Identifier *Identifier
 	Value      Expression
+
+	// Names holds the targets of a destructuring let like `let (q, r) = ...`.
+	// Identifier is nil in that case.
+	Names []*Identifier
 }
 
 func (statement *LetStatement) statementNode()       {}
 func (statement *LetStatement) TokenLiteral() string { return statement.Token.Literal }
+func (statement *LetStatement) IsConstant() bool     { return statement.Token.Type == token.CONST }
 func (statement *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(statement.TokenLiteral() + " ")
-	out.WriteString(statement.Identifier.Value)
+	if statement.Identifier != nil {
+		out.WriteString(statement.Identifier.Value)
+	} else {
+		names := []string{}
+		for _, name := range statement.Names {
+			names = append(names, name.Value)
+		}
+		out.WriteString("(" + strings.Join(names, ", ") + ")")
+	}
 	out.WriteString(" = ")
 
 	if statement.Value != nil {
@@ -81,8 +111,15 @@ func (statement *LetStatement) String() string {
 
 	return out.String()
 }
-func (statement *LetStatement) Line() int   { return statement.Token.Line }
-func (statement *LetStatement) Column() int { return statement.Token.Column }
+func (statement *LetStatement) Line() int           { return statement.Token.Line }
+func (statement *LetStatement) Column() int         { return statement.Token.Column }
+func (statement *LetStatement) Pos() token.Position { return statement.Token.Pos() }
+func (statement *LetStatement) End() token.Position {
+	if statement.Value == nil {
+		return statement.Token.End()
+	}
+	return statement.Value.End()
+}
 
 type Identifier struct {
 	Token token.Token
@@ -94,6 +131,8 @@ func (identifier *Identifier) TokenLiteral() string { return identifier.Token.Li
 func (identifier *Identifier) String() string       { return identifier.Value }
 func (identifier *Identifier) Line() int            { return identifier.Token.Line }
 func (identifier *Identifier) Column() int          { return identifier.Token.Column }
+func (identifier *Identifier) Pos() token.Position  { return identifier.Token.Pos() }
+func (identifier *Identifier) End() token.Position  { return identifier.Token.End() }
 
 type IntegerLiteral struct {
 	Token token.Token
@@ -105,6 +144,8 @@ func (IntegerLiteral *IntegerLiteral) TokenLiteral() string { return IntegerLite
 func (IntegerLiteral *IntegerLiteral) String() string       { return IntegerLiteral.Token.Literal }
 func (IntegerLiteral *IntegerLiteral) Line() int            { return IntegerLiteral.Token.Line }
 func (IntegerLiteral *IntegerLiteral) Column() int          { return IntegerLiteral.Token.Column }
+func (IntegerLiteral *IntegerLiteral) Pos() token.Position  { return IntegerLiteral.Token.Pos() }
+func (IntegerLiteral *IntegerLiteral) End() token.Position  { return IntegerLiteral.Token.End() }
 
 type ReturnStatement struct {
 	Token       token.Token
@@ -115,6 +156,13 @@ func (statement *ReturnStatement) statementNode()       {}
 func (statement *ReturnStatement) TokenLiteral() string { return statement.Token.Literal }
 func (statement *ReturnStatement) Line() int            { return statement.Token.Line }
 func (statement *ReturnStatement) Column() int          { return statement.Token.Column }
+func (statement *ReturnStatement) Pos() token.Position  { return statement.Token.Pos() }
+func (statement *ReturnStatement) End() token.Position {
+	if statement.ReturnValue == nil {
+		return statement.Token.End()
+	}
+	return statement.ReturnValue.End()
+}
 func (statement *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -129,6 +177,40 @@ func (statement *ReturnStatement) String() string {
 	return out.String()
 }
 
+// YieldStatement suspends a generator function and hands Value to the
+// consumer of the generator.
+type YieldStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (statement *YieldStatement) statementNode()       {}
+func (statement *YieldStatement) TokenLiteral() string { return statement.Token.Literal }
+func (statement *YieldStatement) Line() int            { return statement.Token.Line }
+func (statement *YieldStatement) Column() int          { return statement.Token.Column }
+func (statement *YieldStatement) Pos() token.Position  { return statement.Token.Pos() }
+func (statement *YieldStatement) End() token.Position  { return statement.Value.End() }
+func (statement *YieldStatement) String() string {
+	return statement.Token.Literal + " " + statement.Value.String() + ";"
+}
+
+// DeferStatement schedules Expression to be evaluated when the function it is
+// in returns.
+type DeferStatement struct {
+	Token      token.Token
+	Expression Expression
+}
+
+func (statement *DeferStatement) statementNode()       {}
+func (statement *DeferStatement) TokenLiteral() string { return statement.Token.Literal }
+func (statement *DeferStatement) Line() int            { return statement.Token.Line }
+func (statement *DeferStatement) Column() int          { return statement.Token.Column }
+func (statement *DeferStatement) Pos() token.Position  { return statement.Token.Pos() }
+func (statement *DeferStatement) End() token.Position  { return statement.Expression.End() }
+func (statement *DeferStatement) String() string {
+	return statement.Token.Literal + " " + statement.Expression.String() + ";"
+}
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -138,6 +220,13 @@ func (statement *ExpressionStatement) statementNode()       {}
 func (statement *ExpressionStatement) TokenLiteral() string { return statement.TokenLiteral() }
 func (statement *ExpressionStatement) Line() int            { return statement.Token.Line }
 func (statement *ExpressionStatement) Column() int          { return statement.Token.Column }
+func (statement *ExpressionStatement) Pos() token.Position  { return statement.Token.Pos() }
+func (statement *ExpressionStatement) End() token.Position {
+	if statement.Expression == nil {
+		return statement.Token.End()
+	}
+	return statement.Expression.End()
+}
 func (statement *ExpressionStatement) String() string {
 	if statement.Expression == nil {
 		return ""
@@ -156,6 +245,8 @@ func (expression *PrefixExpression) expressionNode()      {}
 func (expression *PrefixExpression) TokenLiteral() string { return expression.Token.Literal }
 func (expression *PrefixExpression) Line() int            { return expression.Token.Line }
 func (expression *PrefixExpression) Column() int          { return expression.Token.Column }
+func (expression *PrefixExpression) Pos() token.Position  { return expression.Token.Pos() }
+func (expression *PrefixExpression) End() token.Position  { return expression.Right.End() }
 func (expression *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -178,6 +269,8 @@ func (expression *InfixExpression) expressionNode()      {}
 func (expression *InfixExpression) TokenLiteral() string { return expression.Token.Literal }
 func (expression *InfixExpression) Line() int            { return expression.Token.Line }
 func (expression *InfixExpression) Column() int          { return expression.Token.Column }
+func (expression *InfixExpression) Pos() token.Position  { return expression.Left.Pos() }
+func (expression *InfixExpression) End() token.Position  { return expression.Right.End() }
 func (expression *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -200,16 +293,31 @@ func (expression *Boolean) TokenLiteral() string { return expression.Token.Liter
 func (expression *Boolean) String() string       { return expression.Token.Literal }
 func (expression *Boolean) Line() int            { return expression.Token.Line }
 func (expression *Boolean) Column() int          { return expression.Token.Column }
+func (expression *Boolean) Pos() token.Position  { return expression.Token.Pos() }
+func (expression *Boolean) End() token.Position  { return expression.Token.End() }
 
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	EndToken   token.Token // the closing `}`
 }
 
 func (block *BlockStatement) statementNode()       {}
 func (block *BlockStatement) TokenLiteral() string { return block.Token.Literal }
 func (block *BlockStatement) Line() int            { return block.Token.Line }
 func (block *BlockStatement) Column() int          { return block.Token.Column }
+func (block *BlockStatement) Pos() token.Position  { return block.Token.Pos() }
+func (block *BlockStatement) End() token.Position {
+	// Blocks made up by the parser, like the bodies of arrow functions, have
+	// no closing brace.
+	if block.EndToken.EndLine != 0 {
+		return block.EndToken.End()
+	}
+	if len(block.Statements) != 0 {
+		return block.Statements[len(block.Statements)-1].End()
+	}
+	return block.Token.End()
+}
 func (block *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -233,6 +341,13 @@ func (expression *IfExpression) expressionNode()      {}
 func (expression *IfExpression) TokenLiteral() string { return expression.Token.Literal }
 func (expression *IfExpression) Line() int            { return expression.Token.Line }
 func (expression *IfExpression) Column() int          { return expression.Token.Column }
+func (expression *IfExpression) Pos() token.Position  { return expression.Token.Pos() }
+func (expression *IfExpression) End() token.Position {
+	if expression.Alternative != nil {
+		return expression.Alternative.End()
+	}
+	return expression.Consequence.End()
+}
 func (expression *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -249,16 +364,58 @@ func (expression *IfExpression) String() string {
 	return out.String()
 }
 
+// ForStatement runs Body once for every value of Iterable, bound to
+// Identifier, or destructured into Names for loops like `for (k, v) in xs`.
+type ForStatement struct {
+	Token      token.Token
+	Identifier *Identifier
+	Names      []*Identifier
+	Iterable   Expression
+	Body       *BlockStatement
+}
+
+func (statement *ForStatement) statementNode()       {}
+func (statement *ForStatement) TokenLiteral() string { return statement.Token.Literal }
+func (statement *ForStatement) Line() int            { return statement.Token.Line }
+func (statement *ForStatement) Column() int          { return statement.Token.Column }
+func (statement *ForStatement) Pos() token.Position  { return statement.Token.Pos() }
+func (statement *ForStatement) End() token.Position  { return statement.Body.End() }
+func (statement *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	if statement.Identifier != nil {
+		out.WriteString(statement.Identifier.Value)
+	} else {
+		names := []string{}
+		for _, name := range statement.Names {
+			names = append(names, name.Value)
+		}
+		out.WriteString("(" + strings.Join(names, ", ") + ")")
+	}
+	out.WriteString(" in ")
+	out.WriteString(statement.Iterable.String())
+	out.WriteString(" ")
+	out.WriteString(statement.Body.String())
+
+	return out.String()
+}
+
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
 	Body       *BlockStatement
+
+	// IsGenerator is set for `fn*` literals, whose body may yield.
+	IsGenerator bool
 }
 
 func (expression *FunctionLiteral) expressionNode()      {}
 func (expression *FunctionLiteral) TokenLiteral() string { return expression.Token.Literal }
 func (expression *FunctionLiteral) Line() int            { return expression.Token.Line }
 func (expression *FunctionLiteral) Column() int          { return expression.Token.Column }
+func (expression *FunctionLiteral) Pos() token.Position  { return expression.Token.Pos() }
+func (expression *FunctionLiteral) End() token.Position  { return expression.Body.End() }
 func (expression *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -268,6 +425,9 @@ func (expression *FunctionLiteral) String() string {
 	}
 
 	out.WriteString(expression.TokenLiteral())
+	if expression.IsGenerator {
+		out.WriteString("*")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(parameters, ","))
 	out.WriteString(") ")
@@ -280,12 +440,15 @@ type CallExpression struct {
 	Token     token.Token
 	Function  Expression // identifier or function literal
 	Arguments []Expression
+	EndToken  token.Token // the closing `)`
 }
 
 func (expression *CallExpression) expressionNode()      {}
 func (expression *CallExpression) TokenLiteral() string { return expression.Token.Literal }
 func (expression *CallExpression) Line() int            { return expression.Function.Line() }
 func (expression *CallExpression) Column() int          { return expression.Function.Column() }
+func (expression *CallExpression) Pos() token.Position  { return expression.Function.Pos() }
+func (expression *CallExpression) End() token.Position  { return expression.EndToken.End() }
 func (expression *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -319,18 +482,23 @@ func (sl *StringLiteral) String() string {
 	return string(out.Bytes())
 }
 
-func (sl *StringLiteral) Line() int   { return sl.Token.Line }
-func (sl *StringLiteral) Column() int { return sl.Token.Column }
+func (sl *StringLiteral) Line() int           { return sl.Token.Line }
+func (sl *StringLiteral) Column() int         { return sl.Token.Column }
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos() }
+func (sl *StringLiteral) End() token.Position { return sl.Token.End() }
 
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
+	EndToken token.Token // the closing `]`
 }
 
 func (ar *ArrayLiteral) expressionNode()      {}
 func (ar *ArrayLiteral) TokenLiteral() string { return ar.Token.Literal }
 func (ar *ArrayLiteral) Line() int            { return ar.Token.Line }
 func (ar *ArrayLiteral) Column() int          { return ar.Token.Column }
+func (ar *ArrayLiteral) Pos() token.Position  { return ar.Token.Pos() }
+func (ar *ArrayLiteral) End() token.Position  { return ar.EndToken.End() }
 func (ar *ArrayLiteral) String() string {
 	var buf bytes.Buffer
 
@@ -346,16 +514,49 @@ func (ar *ArrayLiteral) String() string {
 	return buf.String()
 }
 
+type TupleLiteral struct {
+	Token    token.Token
+	Elements []Expression
+	EndToken token.Token // the closing `)`
+}
+
+func (tl *TupleLiteral) expressionNode()      {}
+func (tl *TupleLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TupleLiteral) Line() int            { return tl.Token.Line }
+func (tl *TupleLiteral) Column() int          { return tl.Token.Column }
+func (tl *TupleLiteral) Pos() token.Position  { return tl.Token.Pos() }
+func (tl *TupleLiteral) End() token.Position  { return tl.EndToken.End() }
+func (tl *TupleLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, element := range tl.Elements {
+		elements = append(elements, element.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	EndToken token.Token // the closing `]`
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *IndexExpression) Line() int            { return ie.Token.Line }
 func (ie *IndexExpression) Column() int          { return ie.Token.Column }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Left.Pos() }
+func (ie *IndexExpression) End() token.Position  { return ie.EndToken.End() }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -368,21 +569,63 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression is `Left[Start:Stop]`. Start and Stop are optional and nil
+// when omitted.
+type SliceExpression struct {
+	Token    token.Token
+	Left     Expression
+	Start    Expression
+	Stop     Expression
+	EndToken token.Token // the closing `]`
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Line() int            { return se.Token.Line }
+func (se *SliceExpression) Column() int          { return se.Token.Column }
+func (se *SliceExpression) Pos() token.Position  { return se.Left.Pos() }
+func (se *SliceExpression) End() token.Position  { return se.EndToken.End() }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.Stop != nil {
+		out.WriteString(se.Stop.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
+}
+
 type HashLiteral struct {
-	Token token.Token
-	Pairs map[Expression]Expression
+	Token    token.Token
+	Pairs    []HashLiteralPair // in source order
+	EndToken token.Token       // the closing `}`
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 func (hl *HashLiteral) Line() int            { return hl.Token.Line }
 func (hl *HashLiteral) Column() int          { return hl.Token.Column }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos() }
+func (hl *HashLiteral) End() token.Position  { return hl.EndToken.End() }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String(), ": ", value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -391,6 +634,105 @@ func (hl *HashLiteral) String() string {
 	return out.String()
 }
 
+// ComprehensionClause is the `for x in xs if condition` part of a
+// comprehension. Like in a for statement the values can be destructured into
+// Names. Condition is nil if there is no if.
+type ComprehensionClause struct {
+	Token      token.Token
+	Identifier *Identifier
+	Names      []*Identifier
+	Iterable   Expression
+	Condition  Expression
+}
+
+func (clause *ComprehensionClause) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	if clause.Identifier != nil {
+		out.WriteString(clause.Identifier.Value)
+	} else {
+		names := []string{}
+		for _, name := range clause.Names {
+			names = append(names, name.Value)
+		}
+		out.WriteString("(" + strings.Join(names, ", ") + ")")
+	}
+	out.WriteString(" in ")
+	out.WriteString(clause.Iterable.String())
+
+	if clause.Condition != nil {
+		out.WriteString(" if ")
+		out.WriteString(clause.Condition.String())
+	}
+
+	return out.String()
+}
+
+// ArrayComprehension is `[element for x in xs if condition]`.
+type ArrayComprehension struct {
+	Token    token.Token
+	Element  Expression
+	Clause   *ComprehensionClause
+	EndToken token.Token // the closing `]`
+}
+
+func (ac *ArrayComprehension) expressionNode()      {}
+func (ac *ArrayComprehension) TokenLiteral() string { return ac.Token.Literal }
+func (ac *ArrayComprehension) Line() int            { return ac.Token.Line }
+func (ac *ArrayComprehension) Column() int          { return ac.Token.Column }
+func (ac *ArrayComprehension) Pos() token.Position  { return ac.Token.Pos() }
+func (ac *ArrayComprehension) End() token.Position  { return ac.EndToken.End() }
+func (ac *ArrayComprehension) String() string {
+	return "[" + ac.Element.String() + " " + ac.Clause.String() + "]"
+}
+
+// HashComprehension is `{key: value for x in xs if condition}`.
+type HashComprehension struct {
+	Token    token.Token
+	Key      Expression
+	Value    Expression
+	Clause   *ComprehensionClause
+	EndToken token.Token // the closing `}`
+}
+
+func (hc *HashComprehension) expressionNode()      {}
+func (hc *HashComprehension) TokenLiteral() string { return hc.Token.Literal }
+func (hc *HashComprehension) Line() int            { return hc.Token.Line }
+func (hc *HashComprehension) Column() int          { return hc.Token.Column }
+func (hc *HashComprehension) Pos() token.Position  { return hc.Token.Pos() }
+func (hc *HashComprehension) End() token.Position  { return hc.EndToken.End() }
+func (hc *HashComprehension) String() string {
+	return "{" + hc.Key.String() + ": " + hc.Value.String() + " " + hc.Clause.String() + "}"
+}
+
+type SetLiteral struct {
+	Token    token.Token
+	Elements []Expression
+	EndToken token.Token // the closing `}`
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) Line() int            { return sl.Token.Line }
+func (sl *SetLiteral) Column() int          { return sl.Token.Column }
+func (sl *SetLiteral) Pos() token.Position  { return sl.Token.Pos() }
+func (sl *SetLiteral) End() token.Position  { return sl.EndToken.End() }
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, element := range sl.Elements {
+		elements = append(elements, element.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type PropertyExpression struct {
 	Token   token.Token
 	Subject Expression
@@ -401,6 +743,8 @@ func (p *PropertyExpression) expressionNode()      {}
 func (p *PropertyExpression) TokenLiteral() string { return p.Token.Literal }
 func (p *PropertyExpression) Line() int            { return p.Name.Line() }
 func (p *PropertyExpression) Column() int          { return p.Name.Column() }
+func (p *PropertyExpression) Pos() token.Position  { return p.Subject.Pos() }
+func (p *PropertyExpression) End() token.Position  { return p.Name.End() }
 func (p *PropertyExpression) String() string {
 	var out bytes.Buffer
 
@@ -410,3 +754,37 @@ func (p *PropertyExpression) String() string {
 
 	return out.String()
 }
+
+// BadExpression is a placeholder for an expression that could not be parsed.
+// Token is the token at which parsing failed.
+type BadExpression struct {
+	Token token.Token
+}
+
+func (be *BadExpression) expressionNode()      {}
+func (be *BadExpression) TokenLiteral() string { return be.Token.Literal }
+func (be *BadExpression) Line() int            { return be.Token.Line }
+func (be *BadExpression) Column() int          { return be.Token.Column }
+func (be *BadExpression) Pos() token.Position  { return be.Token.Pos() }
+func (be *BadExpression) End() token.Position  { return be.Token.End() }
+func (be *BadExpression) String() string       { return "<bad expression>" }
+
+// BadStatement is a placeholder for a statement that could not be parsed.
+// Token is the first and Last the last token of the statement.
+type BadStatement struct {
+	Token token.Token
+	Last  token.Token
+}
+
+func (bs *BadStatement) statementNode()       {}
+func (bs *BadStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BadStatement) Line() int            { return bs.Token.Line }
+func (bs *BadStatement) Column() int          { return bs.Token.Column }
+func (bs *BadStatement) Pos() token.Position  { return bs.Token.Pos() }
+func (bs *BadStatement) End() token.Position {
+	if bs.Last.EndLine == 0 {
+		return bs.Token.End()
+	}
+	return bs.Last.End()
+}
+func (bs *BadStatement) String() string { return "<bad statement>" }
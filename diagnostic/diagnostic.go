@@ -0,0 +1,93 @@
+package diagnostic
+
+import (
+	"fmt"
+
+	"github.com/hendrikbursian/monkey-programming-language/token"
+)
+
+type Severity int
+
+const (
+	ERROR Severity = iota
+	WARNING
+)
+
+func (severity Severity) String() string {
+	switch severity {
+	case ERROR:
+		return "error"
+	case WARNING:
+		return "warning"
+	default:
+		return fmt.Sprintf("severity(%d)", int(severity))
+	}
+}
+
+// Code identifies the kind of a diagnostic. Codes are stable, so tools can
+// rely on them instead of on messages.
+type Code string
+
+const (
+	ILLEGAL_CHARACTER       Code = "illegal-character"
+	UNTERMINATED_STRING     Code = "unterminated-string"
+	UNEXPECTED_TOKEN        Code = "unexpected-token"
+	MISSING_EXPRESSION      Code = "missing-expression"
+	INVALID_INTEGER         Code = "invalid-integer"
+	YIELD_OUTSIDE_GENERATOR Code = "yield-outside-generator"
+	INVALID_ARROW_PARAMETER Code = "invalid-arrow-parameter"
+	DEFER_OUTSIDE_FUNCTION  Code = "defer-outside-function"
+)
+
+type Position = token.Position
+
+// Diagnostic is a problem found in the source, reported by the lexer or the
+// parser. It covers the source from Start up to, but not including, End.
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Message  string
+	Start    Position
+	End      Position
+
+	// Hints suggest how to fix the problem.
+	Hints []string
+}
+
+// AtToken returns a diagnostic that covers tok.
+func AtToken(severity Severity, code Code, tok token.Token, format string, a ...interface{}) *Diagnostic {
+	end := tok.End()
+	if end.Line == 0 {
+		// The token was not read by the lexer.
+		span := SpanOf(tok)
+		end = Position{File: tok.File, Offset: tok.Offset + span.Length, Line: span.Line, Column: span.Column + span.Length}
+	}
+
+	return &Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+		Start:    tok.Pos(),
+		End:      end,
+	}
+}
+
+// WithHint adds a hint to the diagnostic and returns it.
+func (diagnostic *Diagnostic) WithHint(format string, a ...interface{}) *Diagnostic {
+	diagnostic.Hints = append(diagnostic.Hints, fmt.Sprintf(format, a...))
+	return diagnostic
+}
+
+// Span returns the part of the first line of the diagnostic it covers.
+func (diagnostic *Diagnostic) Span() Span {
+	length := 1
+	if diagnostic.End.Line == diagnostic.Start.Line && diagnostic.End.Column > diagnostic.Start.Column {
+		length = diagnostic.End.Column - diagnostic.Start.Column
+	}
+
+	return Span{Line: diagnostic.Start.Line, Column: diagnostic.Start.Column, Length: length}
+}
+
+func (diagnostic *Diagnostic) String() string {
+	return fmt.Sprintf("%s at %s", diagnostic.Message, diagnostic.Start)
+}
@@ -0,0 +1,117 @@
+// Package diagnostic renders errors together with the part of the source they
+// point at.
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hendrikbursian/monkey-programming-language/token"
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorError = "\x1b[1;31m"
+	colorNote  = "\x1b[1;34m"
+)
+
+// Span is a part of a single line of source, starting at Line:Column and
+// covering Length bytes.
+type Span struct {
+	Line   int
+	Column int
+	Length int
+}
+
+// SpanOf returns the span of tok in the source it was read from. Tokens that
+// span multiple lines are cut off at the end of the first line.
+func SpanOf(tok token.Token) Span {
+	if tok.EndLine == tok.Line && tok.EndColumn > tok.Column {
+		return Span{Line: tok.Line, Column: tok.Column, Length: tok.EndColumn - tok.Column}
+	}
+
+	length := len(tok.Literal)
+	if tok.Type == token.STRING {
+		// The literal of a string does not include the quotes.
+		length += 2
+	}
+
+	return Span{Line: tok.Line, Column: tok.Column, Length: length}
+}
+
+// Renderer renders messages about Source.
+type Renderer struct {
+	Source string
+
+	// Color enables ANSI colors.
+	Color bool
+}
+
+// Render writes message followed by the line of the source span is on, with
+// span underlined as `^~~~`. If the line does not exist in the source, only
+// message is written.
+func (renderer *Renderer) Render(out io.Writer, message string, span Span) {
+	fmt.Fprintf(out, "%s\n", renderer.paint(colorError, message))
+
+	lines := strings.Split(renderer.Source, "\n")
+	if span.Line < 1 || span.Line > len(lines) {
+		return
+	}
+
+	line := strings.TrimSuffix(lines[span.Line-1], "\r")
+	gutter := fmt.Sprintf("%d | ", span.Line)
+	empty := strings.Repeat(" ", len(gutter)-2) + "| "
+
+	fmt.Fprintf(out, "%s%s\n", renderer.paint(colorNote, gutter), line)
+	fmt.Fprintf(out, "%s%s%s\n", renderer.paint(colorNote, empty), indentation(line, span.Column), renderer.paint(colorError, marker(line, span)))
+}
+
+// RenderDiagnostic writes diagnostic like Render, headed by its severity and
+// code and followed by its hints.
+func (renderer *Renderer) RenderDiagnostic(out io.Writer, diagnostic *Diagnostic) {
+	message := fmt.Sprintf("%s[%s]: %s", diagnostic.Severity, diagnostic.Code, diagnostic)
+	renderer.Render(out, message, diagnostic.Span())
+
+	for _, hint := range diagnostic.Hints {
+		fmt.Fprintf(out, "%s %s\n", renderer.paint(colorNote, "hint:"), hint)
+	}
+}
+
+func (renderer *Renderer) paint(color string, text string) string {
+	if !renderer.Color {
+		return text
+	}
+
+	return color + text + colorReset
+}
+
+// indentation returns the whitespace that moves the marker to column. Tabs of
+// the line are kept so that the marker lines up with the source.
+func indentation(line string, column int) string {
+	var out strings.Builder
+
+	for i := 0; i < column-1; i++ {
+		if i < len(line) && line[i] == '\t' {
+			out.WriteByte('\t')
+		} else {
+			out.WriteByte(' ')
+		}
+	}
+
+	return out.String()
+}
+
+// marker returns the underline of span, which is cut off at the end of line.
+func marker(line string, span Span) string {
+	length := span.Length
+	if rest := len(line) - span.Column + 1; length > rest {
+		length = rest
+	}
+
+	if length < 1 {
+		length = 1
+	}
+
+	return "^" + strings.Repeat("~", length-1)
+}
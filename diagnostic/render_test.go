@@ -0,0 +1,59 @@
+package diagnostic
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/hendrikbursian/monkey-programming-language/token"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		source   string
+		span     Span
+		color    bool
+		expected string
+	}{
+		{"1 + true", Span{1, 5, 4}, false, "msg\n1 | 1 + true\n  |     ^~~~\n"},
+		{"let x = 1;\nx + y", Span{2, 5, 1}, false, "msg\n2 | x + y\n  |     ^\n"},
+		{"fn() {\n\tx + y }", Span{2, 6, 1}, false, "msg\n2 | \tx + y }\n  | \t    ^\n"},
+		{"let s = \"abc", Span{1, 9, 10}, false, "msg\n1 | let s = \"abc\n  |         ^~~~\n"},
+		{"x", Span{1, 2, 0}, false, "msg\n1 | x\n  |  ^\n"},
+		{"x", Span{3, 1, 1}, false, "msg\n"},
+		{"x", Span{1, 1, 1}, true, "\x1b[1;31mmsg\x1b[0m\n\x1b[1;34m1 | \x1b[0mx\n\x1b[1;34m  | \x1b[0m\x1b[1;31m^\x1b[0m\n"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			var out bytes.Buffer
+			renderer := &Renderer{Source: tt.source, Color: tt.color}
+			renderer.Render(&out, "msg", tt.span)
+
+			if out.String() != tt.expected {
+				t.Errorf("wrong output. want=%q, got=%q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestRenderDiagnostic(t *testing.T) {
+	d := AtToken(ERROR, UNEXPECTED_TOKEN, token.Token{Type: token.RIGHT_PAREN, Literal: ")", Line: 1, Column: 5}, "unexpected %s", ")").
+		WithHint("remove it")
+
+	var out bytes.Buffer
+	renderer := &Renderer{Source: "f(1))"}
+	renderer.RenderDiagnostic(&out, d)
+
+	expected := "error[unexpected-token]: unexpected ) at 1:5\n1 | f(1))\n  |     ^\nhint: remove it\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestSpanOf(t *testing.T) {
+	span := SpanOf(token.Token{Type: token.STRING, Literal: "ab", Line: 2, Column: 3})
+	if span != (Span{2, 3, 4}) {
+		t.Errorf("wrong span. got=%+v", span)
+	}
+}
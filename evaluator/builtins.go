@@ -1,82 +1,574 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"unicode/utf8"
 
+	"github.com/hendrikbursian/monkey-programming-language/ast"
 	"github.com/hendrikbursian/monkey-programming-language/object"
+	"github.com/hendrikbursian/monkey-programming-language/token"
 )
 
-var builtins map[string]*object.Builtin = map[string]*object.Builtin{
-	"puts": {
-		Fn: func(args ...object.Object) (object.Object, error) {
-			for _, arg := range args {
-				os.Stdout.WriteString(arg.Inspect())
+// PRETTY_WIDTH is the default line width used by the `pretty` builtin.
+const PRETTY_WIDTH = 80
+
+// MAX_CURRY_ARITY is the largest number of arguments curry accepts.
+const MAX_CURRY_ARITY = 255
+
+var builtins map[string]*object.Builtin
+
+// builtins is initialized in init, because builtins like map call back into
+// the evaluator, which in turn looks up builtins.
+func init() {
+	builtins = map[string]*object.Builtin{
+		"puts": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				for _, arg := range args {
+					os.Stdout.WriteString(arg.Inspect())
+					os.Stdout.WriteString("\n")
+				}
+
+				return UNIT, nil
+			},
+		},
+		"pretty": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 && len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to pretty, got=%d, want=1 or 2", len(args))
+				}
+
+				width := PRETTY_WIDTH
+				if len(args) == 2 {
+					widthObj, ok := args[1].(*object.Integer)
+					if !ok {
+						return nil, fmt.Errorf("second argument to pretty has to be an integer, got %s instead", args[1].Type())
+					}
+					width = int(widthObj.Value)
+				}
+
+				os.Stdout.WriteString(object.Pretty(args[0], width))
 				os.Stdout.WriteString("\n")
-			}
-
-			return nil, nil
-		},
-	},
-	"l": {
-		Fn: func(args ...object.Object) (object.Object, error) {
-			if len(args) != 1 {
-				return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(args), 1)
-			}
-			switch arg := args[0].(type) {
-			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}, nil
-			case *object.Array:
-				return &object.Integer{Value: int64(len(arg.Elements))}, nil
-			default:
-				return nil, fmt.Errorf("argument to `l` not supported. got=%s", arg.Type())
-			}
-		},
-	},
-	"push": {
-		Fn: func(args ...object.Object) (object.Object, error) {
-			if len(args) != 2 {
-				return nil, fmt.Errorf("wrong number of arguments to push. got=%d, want=%d", len(args), 2)
-			}
-
-			arrObj, ok := args[0].(*object.Array)
-			if !ok {
-				return nil, fmt.Errorf("first argument to push has to be an array, got %s instead", args[0].Type())
-			}
-
-			arrObj.Elements = append(arrObj.Elements, args[1])
-
-			return arrObj, nil
-		},
-	},
-	"first": {
-		Fn: func(args ...object.Object) (object.Object, error) {
-			if len(args) != 1 {
-				return nil, fmt.Errorf("wrong number of arguments to first, got=%d, want=%d", len(args), 1)
-			}
-
-			arrObj, ok := args[0].(*object.Array)
-			if !ok {
-				return nil, fmt.Errorf("first argument to first has to be an array, got %s instead", args[0].Type())
-
-			}
-
-			return wrapMaybe(arrObj.Elements[0]), nil
-		},
-	},
-	"last": {
-		Fn: func(args ...object.Object) (object.Object, error) {
-			if len(args) != 1 {
-				return nil, fmt.Errorf("wrong number of arguments to last, got=%d, want=%d", len(args), 1)
-			}
-
-			arrObj, ok := args[0].(*object.Array)
-			if !ok {
-				return nil, fmt.Errorf("first argument to last has to be an array, got %s instead", args[0].Type())
-
-			}
-
-			return wrapMaybe(arrObj.Elements[len(arrObj.Elements)-1]), nil
-		},
-	},
+
+				return UNIT, nil
+			},
+		},
+		"l": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(args), 1)
+				}
+				switch arg := args[0].(type) {
+				case *object.String:
+					return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}, nil
+				case *object.Array:
+					return &object.Integer{Value: int64(len(arg.Elements))}, nil
+				case *object.Tuple:
+					return &object.Integer{Value: int64(len(arg.Elements))}, nil
+				case *object.Set:
+					return &object.Integer{Value: int64(len(arg.Elements))}, nil
+				case *object.Vector:
+					return &object.Integer{Value: int64(arg.Len())}, nil
+				case *object.Dict:
+					return &object.Integer{Value: int64(arg.Len())}, nil
+				case *object.Range:
+					return &object.Integer{Value: int64(arg.Len())}, nil
+				default:
+					return nil, fmt.Errorf("argument to `l` not supported. got=%s", arg.Type())
+				}
+			},
+		},
+		"push": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to push. got=%d, want=%d", len(args), 2)
+				}
+
+				arrObj, ok := args[0].(*object.Array)
+				if !ok {
+					return nil, fmt.Errorf("first argument to push has to be an array, got %s instead", args[0].Type())
+				}
+
+				if arrObj.Frozen {
+					return nil, fmt.Errorf("cannot push to frozen array")
+				}
+
+				arrObj.Elements = append(arrObj.Elements, args[1])
+
+				return arrObj, nil
+			},
+		},
+		"freeze": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to freeze, got=%d, want=%d", len(args), 1)
+				}
+
+				object.Freeze(args[0])
+
+				return args[0], nil
+			},
+		},
+		"first": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to first, got=%d, want=%d", len(args), 1)
+				}
+
+				if vector, ok := args[0].(*object.Vector); ok {
+					if vector.Len() == 0 {
+						return &EMPTY_MAYBE, nil
+					}
+					return wrapMaybe(vector.Get(0)), nil
+				}
+
+				arrObj, ok := args[0].(*object.Array)
+				if !ok {
+					return nil, fmt.Errorf("first argument to first has to be an array, got %s instead", args[0].Type())
+
+				}
+
+				if len(arrObj.Elements) == 0 {
+					return &EMPTY_MAYBE, nil
+				}
+				return wrapMaybe(arrObj.Elements[0]), nil
+			},
+		},
+		"last": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to last, got=%d, want=%d", len(args), 1)
+				}
+
+				if vector, ok := args[0].(*object.Vector); ok {
+					if vector.Len() == 0 {
+						return &EMPTY_MAYBE, nil
+					}
+					return wrapMaybe(vector.Get(vector.Len() - 1)), nil
+				}
+
+				arrObj, ok := args[0].(*object.Array)
+				if !ok {
+					return nil, fmt.Errorf("first argument to last has to be an array, got %s instead", args[0].Type())
+
+				}
+
+				if len(arrObj.Elements) == 0 {
+					return &EMPTY_MAYBE, nil
+				}
+				return wrapMaybe(arrObj.Elements[len(arrObj.Elements)-1]), nil
+			},
+		},
+		"divmod": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to divmod, got=%d, want=%d", len(args), 2)
+				}
+
+				dividend, ok := args[0].(*object.Integer)
+				if !ok {
+					return nil, fmt.Errorf("first argument to divmod has to be an integer, got %s instead", args[0].Type())
+				}
+
+				divisor, ok := args[1].(*object.Integer)
+				if !ok {
+					return nil, fmt.Errorf("second argument to divmod has to be an integer, got %s instead", args[1].Type())
+				}
+
+				if divisor.Value == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+
+				return &object.Tuple{Elements: []object.Object{
+					&object.Integer{Value: dividend.Value / divisor.Value},
+					&object.Integer{Value: dividend.Value % divisor.Value},
+				}}, nil
+			},
+		},
+		"set": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) > 1 {
+					return nil, fmt.Errorf("wrong number of arguments to set, got=%d, want=0 or 1", len(args))
+				}
+
+				set := object.NewSet()
+				if len(args) == 0 {
+					return set, nil
+				}
+
+				elements, err := collect(frame, "set", args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				for _, element := range elements {
+					if _, ok := toHashable(element); !ok {
+						return nil, fmt.Errorf("cannot use type %s as element of set", element.Type())
+					}
+					set.Add(element)
+				}
+
+				return set, nil
+			},
+		},
+		"array": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to array, got=%d, want=%d", len(args), 1)
+				}
+
+				elements, err := collect(frame, "array", args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				return &object.Array{Elements: elements}, nil
+			},
+		},
+		"vector": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) > 1 {
+					return nil, fmt.Errorf("wrong number of arguments to vector, got=%d, want=0 or 1", len(args))
+				}
+
+				if len(args) == 0 {
+					return object.NewVector(), nil
+				}
+
+				elements, err := collect(frame, "vector", args[0])
+				if err != nil {
+					return nil, err
+				}
+
+				return object.NewVector(elements...), nil
+			},
+		},
+		"dict": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) > 1 {
+					return nil, fmt.Errorf("wrong number of arguments to dict, got=%d, want=0 or 1", len(args))
+				}
+
+				dict := object.NewDict()
+				if len(args) == 0 {
+					return dict, nil
+				}
+
+				hash, ok := args[0].(*object.Hash)
+				if !ok {
+					return nil, fmt.Errorf("argument to dict has to be a hash, got %s instead", args[0].Type())
+				}
+
+				for _, key := range hash.Keys {
+					dict = dict.Set(key, hash.Pairs[key])
+				}
+
+				return dict, nil
+			},
+		},
+		"pairs": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to pairs, got=%d, want=%d", len(args), 1)
+				}
+
+				var pairs []object.HashPair
+				switch coll := args[0].(type) {
+				case *object.Hash:
+					pairs = coll.Ordered()
+				case *object.Dict:
+					pairs = coll.Pairs()
+				default:
+					return nil, fmt.Errorf("argument to pairs has to be a hash or dict, got %s instead", args[0].Type())
+				}
+
+				elements := make([]object.Object, 0, len(pairs))
+				for _, pair := range pairs {
+					elements = append(elements, &object.Tuple{Elements: []object.Object{pair.Key, pair.Value}})
+				}
+
+				return &object.Array{Elements: elements}, nil
+			},
+		},
+		"curry": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) != 1 && len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to curry, got=%d, want=1 or 2", len(args))
+				}
+
+				arity := 0
+				switch fn := args[0].(type) {
+				case *object.Function:
+					arity = len(fn.Parameters)
+				case *object.Builtin:
+					if len(args) != 2 {
+						return nil, fmt.Errorf("curry needs the number of arguments of a builtin function as second argument")
+					}
+				default:
+					return nil, fmt.Errorf("first argument to curry has to be a function, got %s instead", args[0].Type())
+				}
+
+				if len(args) == 2 {
+					arityObj, ok := args[1].(*object.Integer)
+					if !ok {
+						return nil, fmt.Errorf("second argument to curry has to be an integer, got %s instead", args[1].Type())
+					}
+					if arityObj.Value < 0 || arityObj.Value > MAX_CURRY_ARITY {
+						return nil, fmt.Errorf("number of arguments passed to curry has to be between 0 and %d, got %d", MAX_CURRY_ARITY, arityObj.Value)
+					}
+					arity = int(arityObj.Value)
+				}
+
+				return curry(args[0], arity, frame.Line, frame.Column), nil
+			},
+		},
+		"conj": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to conj, got=%d, want=%d", len(args), 2)
+				}
+
+				vector, ok := args[0].(*object.Vector)
+				if !ok {
+					return nil, fmt.Errorf("first argument to conj has to be a vector, got %s instead", args[0].Type())
+				}
+
+				return vector.Append(args[1]), nil
+			},
+		},
+		"assoc": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 3 {
+					return nil, fmt.Errorf("wrong number of arguments to assoc, got=%d, want=%d", len(args), 3)
+				}
+
+				switch coll := args[0].(type) {
+				case *object.Vector:
+					index, ok := args[1].(*object.Integer)
+					if !ok {
+						return nil, fmt.Errorf("cannot use %s as index for vector", args[1].Type())
+					}
+
+					switch {
+					case int(index.Value) == coll.Len():
+						return coll.Append(args[2]), nil
+					case index.Value < 0 || int(index.Value) > coll.Len():
+						return nil, fmt.Errorf("index %d out of range for vector of length %d", index.Value, coll.Len())
+					default:
+						return coll.Set(int(index.Value), args[2]), nil
+					}
+				case *object.Dict:
+					key, ok := toHashable(args[1])
+					if !ok {
+						return nil, fmt.Errorf("cannot use type %s as key for dict", args[1].Type())
+					}
+
+					return coll.Set(key.HashKey(), object.HashPair{Key: args[1], Value: args[2]}), nil
+				default:
+					return nil, fmt.Errorf("first argument to assoc has to be a vector or dict, got %s instead", args[0].Type())
+				}
+			},
+		},
+		"dissoc": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to dissoc, got=%d, want=%d", len(args), 2)
+				}
+
+				dict, ok := args[0].(*object.Dict)
+				if !ok {
+					return nil, fmt.Errorf("first argument to dissoc has to be a dict, got %s instead", args[0].Type())
+				}
+
+				key, ok := toHashable(args[1])
+				if !ok {
+					return dict, nil
+				}
+
+				return dict.Delete(key.HashKey()), nil
+			},
+		},
+		"rest": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to rest, got=%d, want=%d", len(args), 1)
+				}
+
+				switch coll := args[0].(type) {
+				case *object.Vector:
+					return coll.Rest(), nil
+				case *object.Array:
+					if len(coll.Elements) == 0 {
+						return &object.Array{}, nil
+					}
+					return &object.Array{Elements: append([]object.Object{}, coll.Elements[1:]...)}, nil
+				default:
+					return nil, fmt.Errorf("first argument to rest has to be an array or vector, got %s instead", args[0].Type())
+				}
+			},
+		},
+		"iter": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to iter, got=%d, want=%d", len(args), 1)
+				}
+
+				iterator, ok := iterate(frame, args[0])
+				if !ok {
+					return nil, fmt.Errorf("argument to iter has to be iterable, got %s instead", args[0].Type())
+				}
+
+				return &object.Sequence{Iterator: iterator}, nil
+			},
+		},
+		"next": {
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to next, got=%d, want=%d", len(args), 1)
+				}
+
+				sequence, ok := args[0].(*object.Sequence)
+				if !ok {
+					return nil, fmt.Errorf("first argument to next has to be a sequence, got %s instead", args[0].Type())
+				}
+
+				value, ok := sequence.Iterator.Next()
+				if !ok {
+					return &EMPTY_MAYBE, nil
+				}
+
+				if err, ok := value.(*object.Error); ok {
+					if err.Line == 0 {
+						// Errors of the iterator itself are positioned at the call.
+						return nil, errors.New(err.Message)
+					}
+					return nil, err
+				}
+
+				return wrapMaybe(value), nil
+			},
+		},
+		"map": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to map, got=%d, want=%d", len(args), 2)
+				}
+
+				iterator, ok := iterate(frame, args[0])
+				if !ok {
+					return nil, fmt.Errorf("first argument to map has to be iterable, got %s instead", args[0].Type())
+				}
+
+				if !isCallable(args[1]) {
+					return nil, fmt.Errorf("second argument to map has to be a function, got %s instead", args[1].Type())
+				}
+
+				return &object.Sequence{Iterator: lazyMap(frame, iterator, args[1])}, nil
+			},
+		},
+		"filter": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to filter, got=%d, want=%d", len(args), 2)
+				}
+
+				iterator, ok := iterate(frame, args[0])
+				if !ok {
+					return nil, fmt.Errorf("first argument to filter has to be iterable, got %s instead", args[0].Type())
+				}
+
+				if !isCallable(args[1]) {
+					return nil, fmt.Errorf("second argument to filter has to be a function, got %s instead", args[1].Type())
+				}
+
+				return &object.Sequence{Iterator: lazyFilter(frame, iterator, args[1])}, nil
+			},
+		},
+		"take": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("wrong number of arguments to take, got=%d, want=%d", len(args), 2)
+				}
+
+				iterator, ok := iterate(frame, args[0])
+				if !ok {
+					return nil, fmt.Errorf("first argument to take has to be iterable, got %s instead", args[0].Type())
+				}
+
+				n, ok := args[1].(*object.Integer)
+				if !ok {
+					return nil, fmt.Errorf("second argument to take has to be an integer, got %s instead", args[1].Type())
+				}
+
+				return &object.Sequence{Iterator: lazyTake(iterator, n.Value)}, nil
+			},
+		},
+		"zip": {
+			FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("wrong number of arguments to zip, got=%d, want at least 2", len(args))
+				}
+
+				iterators := make([]object.Iterator, 0, len(args))
+				for i, arg := range args {
+					iterator, ok := iterate(frame, arg)
+					if !ok {
+						return nil, fmt.Errorf("argument %d to zip has to be iterable, got %s instead", i+1, arg.Type())
+					}
+					iterators = append(iterators, iterator)
+				}
+
+				return &object.Sequence{Iterator: lazyZip(iterators)}, nil
+			},
+		},
+	}
+}
+
+// curry returns function as a regular function that takes one argument at a
+// time: a function of arity 2 becomes `fn(_1) { fn(_2) { _0(_1, _2) } }` with
+// _0 bound to function. Identifiers cannot contain digits, so the names cannot
+// clash with identifiers of the program. The synthesized code is positioned
+// at line and column, the position of the call to curry.
+func curry(function object.Object, arity, line, column int) object.Object {
+	if arity < 1 {
+		return function
+	}
+
+	env := object.NewEnvironment()
+	if fn, ok := function.(*object.Function); ok {
+		// Settings of the environment, like the condition mode, still apply.
+		env = object.NewEnclosedEnvironment(fn.Env)
+	}
+	env.Set("_0", function)
+
+	parameters := make([]*ast.Identifier, arity)
+	arguments := make([]ast.Expression, arity)
+	for i := range parameters {
+		parameters[i] = syntheticIdentifier(fmt.Sprintf("_%d", i+1), line, column)
+		arguments[i] = parameters[i]
+	}
+
+	var body ast.Expression = &ast.CallExpression{
+		Token:     token.Token{Type: token.LEFT_PAREN, Literal: "(", Line: line, Column: column},
+		Function:  syntheticIdentifier("_0", line, column),
+		Arguments: arguments,
+	}
+
+	for i := arity - 1; i >= 0; i-- {
+		body = &ast.FunctionLiteral{
+			Token:      token.Token{Type: token.FUNCTION, Literal: "fn", Line: line, Column: column},
+			Parameters: []*ast.Identifier{parameters[i]},
+			Body: &ast.BlockStatement{
+				Token:      token.Token{Type: token.LEFT_CURLY_BRACE, Literal: "{", Line: line, Column: column},
+				Statements: []ast.Statement{&ast.ExpressionStatement{Token: token.Token{Type: token.LEFT_CURLY_BRACE, Literal: "{", Line: line, Column: column}, Expression: body}},
+			},
+		}
+	}
+
+	return evalFunction(body.(*ast.FunctionLiteral), env)
+}
+
+func syntheticIdentifier(name string, line, column int) *ast.Identifier {
+	return &ast.Identifier{Token: token.Token{Type: token.IDENTIFIER, Literal: name, Line: line, Column: column}, Value: name}
 }
@@ -0,0 +1,52 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hendrikbursian/monkey-programming-language/ast"
+	"github.com/hendrikbursian/monkey-programming-language/object"
+)
+
+// DEFAULT_MAX_CALL_DEPTH limits the depth of non-tail calls unless another
+// limit is set with Environment.SetMaxCallDepth. It keeps deep recursion well
+// below the size at which the Go runtime aborts with a stack overflow.
+const DEFAULT_MAX_CALL_DEPTH = 10000
+
+// ANONYMOUS_FUNCTION is the name of frames of functions that are not called
+// through an identifier.
+const ANONYMOUS_FUNCTION = "<anonymous>"
+
+// callChainLength is the number of innermost frames that are listed when the
+// maximum call depth is exceeded.
+const callChainLength = 5
+
+func maxCallDepth(env *object.Environment) int {
+	if depth := env.MaxCallDepth(); depth > 0 {
+		return depth
+	}
+
+	return DEFAULT_MAX_CALL_DEPTH
+}
+
+// calleeName returns the name under which function is called.
+func calleeName(function ast.Expression) string {
+	if identifier, ok := function.(*ast.Identifier); ok {
+		return identifier.Value
+	}
+
+	return ANONYMOUS_FUNCTION
+}
+
+func newRecursionError(frame *object.Frame) *object.Error {
+	calls := []string{}
+	for f := frame; f != nil && len(calls) < callChainLength; f = f.Parent {
+		calls = append(calls, fmt.Sprintf("%s at %d:%d", f.Function, f.Line, f.Column))
+	}
+
+	if hidden := frame.Depth - len(calls); hidden > 0 {
+		calls = append(calls, fmt.Sprintf("%d more", hidden))
+	}
+
+	return newError(frame.Line, frame.Column, "maximum recursion depth exceeded, called from %s", strings.Join(calls, " <- "))
+}
@@ -0,0 +1,31 @@
+package evaluator
+
+import (
+	"github.com/hendrikbursian/monkey-programming-language/ast"
+	"github.com/hendrikbursian/monkey-programming-language/object"
+)
+
+func evalDeferStatement(node *ast.DeferStatement, env *object.Environment) object.Object {
+	deferred := env.Defer(func() object.Object {
+		return Eval(node.Expression, env)
+	})
+	if !deferred {
+		return newError(node.Line(), node.Column(), "defer outside of function")
+	}
+
+	return UNIT
+}
+
+// runDeferred runs the code deferred by a call, last deferred first, after the
+// body of the call evaluated to result. Deferred code runs even if result is
+// an error. An error of deferred code replaces result, unless result is an
+// error already.
+func runDeferred(deferred []func() object.Object, result object.Object) object.Object {
+	for i := len(deferred) - 1; i >= 0; i-- {
+		if value := deferred[i](); isError(value) && !isError(result) {
+			result = value
+		}
+	}
+
+	return result
+}
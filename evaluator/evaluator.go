@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/hendrikbursian/monkey-programming-language/ast"
 	"github.com/hendrikbursian/monkey-programming-language/object"
+	"math"
 	"strings"
 )
 
@@ -11,8 +12,13 @@ var (
 	TRUE        = &object.Boolean{Value: true}
 	FALSE       = &object.Boolean{Value: false}
 	EMPTY_MAYBE = object.Maybe{Value: nil}
+	UNIT        = &object.Unit{}
 )
 
+// MAX_STRING_LENGTH is the length in bytes of the longest string that string
+// repetition produces.
+const MAX_STRING_LENGTH = 1 << 28
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
@@ -32,10 +38,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalReturnStatement(node, env)
 	case *ast.LetStatement:
 		return evalLetStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.YieldStatement:
+		return evalYieldStatement(node, env)
+	case *ast.DeferStatement:
+		return evalDeferStatement(node, env)
 	case *ast.CallExpression:
 		return evalCallExpression(node, env)
 	case *ast.IndexExpression:
 		return evalIndexExpression(node, env)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 	case *ast.PropertyExpression:
 		return evalPropertyExpression(node, env)
 
@@ -49,21 +63,64 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalFunction(node, env)
 	case *ast.ArrayLiteral:
 		return evalArray(node, env)
+	case *ast.TupleLiteral:
+		return evalTuple(node, env)
+	case *ast.SetLiteral:
+		return evalSetLiteral(node, env)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.ArrayComprehension:
+		return evalArrayComprehension(node, env)
+	case *ast.HashComprehension:
+		return evalHashComprehension(node, env)
 	case *ast.Boolean:
 		if node.Value {
 			return TRUE
 		} else {
 			return FALSE
 		}
+	case *ast.BadExpression, *ast.BadStatement:
+		return newError(node.Line(), node.Column(), "cannot evaluate code with syntax errors")
 	default:
-		return nil
+		return UNIT
 	}
 }
 
+// EvalSafely is Eval for entrypoints like embedders and the REPL. A panic
+// while evaluating, which is a bug in the evaluator or in a builtin, is
+// returned as *object.Error instead of taking down the host.
+func EvalSafely(node ast.Node, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = internalError(node, r)
+		}
+	}()
+
+	return Eval(node, env)
+}
+
+// ApplyFunctionSafely is ApplyFunction with the guarantee of EvalSafely.
+func ApplyFunctionSafely(function object.Object, args ...object.Object) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError(0, 0, "internal error: %v", r)
+		}
+	}()
+
+	return ApplyFunction(function, args...)
+}
+
+func internalError(node ast.Node, r interface{}) *object.Error {
+	return newError(node.Line(), node.Column(), "internal error: %v", r)
+}
+
 func evalReturnStatement(node *ast.ReturnStatement, env *object.Environment) object.Object {
-	value := Eval(node.ReturnValue, env)
+	var value object.Object
+	if call, ok := node.ReturnValue.(*ast.CallExpression); ok {
+		value = evalTailCall(call, env)
+	} else {
+		value = Eval(node.ReturnValue, env)
+	}
 	if isError(value) {
 		return value
 	}
@@ -71,14 +128,14 @@ func evalReturnStatement(node *ast.ReturnStatement, env *object.Environment) obj
 }
 
 func evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
-	var result object.Object
+	var result object.Object = UNIT
 
 	for _, statement := range statements {
 		result = Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
-			return result.Value
+			return resolveTailCall(result.Value)
 		case *object.Error:
 			return result
 		}
@@ -95,7 +152,7 @@ func evalPrefixExpression(node *ast.PrefixExpression, env *object.Environment) o
 
 	switch node.Operator {
 	case "!":
-		return evalBangOperatorExpression(node, right)
+		return evalBangOperatorExpression(node, right, env)
 	case "-":
 		return evalMinusOperatorExpression(node, right)
 	default:
@@ -103,15 +160,15 @@ func evalPrefixExpression(node *ast.PrefixExpression, env *object.Environment) o
 	}
 }
 
-func evalBangOperatorExpression(node *ast.PrefixExpression, right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	default:
-		return newError(node.Line(), node.Column(), "unknown operator: %s%s", node.Operator, right.Type())
+// evalBangOperatorExpression negates right, which is treated like the
+// condition of an if expression.
+func evalBangOperatorExpression(node *ast.PrefixExpression, right object.Object, env *object.Environment) object.Object {
+	holds, ok := conditionHolds(right, env.ConditionMode())
+	if !ok {
+		return newError(node.Right.Line(), node.Right.Column(), "condition must be BOOLEAN, got %s", right.Type())
 	}
+
+	return getBooleanObject(!holds)
 }
 
 func evalMinusOperatorExpression(node *ast.PrefixExpression, right object.Object) object.Object {
@@ -130,29 +187,107 @@ func evalInfixExpression(node *ast.InfixExpression, env *object.Environment) obj
 		return left
 	}
 
+	if node.Operator == "|>" {
+		return evalPipeExpression(node, left, env)
+	}
+
 	right := Eval(node.Right, env)
 	if isError(right) {
 		return right
 	}
 
+	if node.Operator == "in" {
+		return evalInExpression(node, left, right)
+	}
+
 	switch {
+	case (node.Operator == ">>" || node.Operator == "<<") && isCallable(left) && isCallable(right):
+		if node.Operator == "<<" {
+			return compose(right, left)
+		}
+		return compose(left, right)
 	case left.Type() == object.INTEGER_OBJECT && right.Type() == object.INTEGER_OBJECT:
 		return evalIntegerInfixExpression(node, left, right)
+	case left.Type() == object.SET_OBJECT && right.Type() == object.SET_OBJECT:
+		return evalSetInfixExpression(node, left, right)
 	case left.Type() == object.STRING_OBJECT && right.Type() == object.STRING_OBJECT:
 		return evalStringInfixExpression(node, left, right)
 	case left.Type() == object.STRING_OBJECT && right.Type() == object.INTEGER_OBJECT:
 		return evalStringIntegerInfixExpression(node, left, right)
 	case node.Operator == "==":
-		return getBooleanObject(left == right)
+		return getBooleanObject(object.Equal(left, right))
 	case node.Operator == "!=":
-		return getBooleanObject(left != right)
+		return getBooleanObject(!object.Equal(left, right))
 	case left.Type() != right.Type():
 		return newError(node.Right.Line(), node.Right.Column(), "type mismatch: %s %s %s", left.Type(), node.Operator, right.Type())
+	case node.Operator == "<" || node.Operator == ">":
+		return evalComparison(node, left, right)
 	default:
 		return newError(node.Line(), node.Column(), "unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
 	}
 }
 
+func evalComparison(node *ast.InfixExpression, left, right object.Object) object.Object {
+	result, ok := object.Compare(left, right)
+	if !ok {
+		return newError(node.Line(), node.Column(), "cannot compare %s %s %s", left.Type(), node.Operator, right.Type())
+	}
+
+	if node.Operator == "<" {
+		return getBooleanObject(result < 0)
+	}
+
+	return getBooleanObject(result > 0)
+}
+
+// evalPipeExpression evaluates `left |> right`. If right is a call, left is
+// passed as its first argument, otherwise right is called with left.
+func evalPipeExpression(node *ast.InfixExpression, left object.Object, env *object.Environment) object.Object {
+	call, ok := node.Right.(*ast.CallExpression)
+	if !ok {
+		function := Eval(node.Right, env)
+		if isError(function) {
+			return function
+		}
+
+		frame := env.Frame().Push(calleeName(node.Right), node.Right.Line(), node.Right.Column())
+		return applyFunction(frame, function, []object.Object{left})
+	}
+
+	function := Eval(call.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args := evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	frame := env.Frame().Push(calleeName(call.Function), call.Line(), call.Column())
+	return applyFunction(frame, function, append([]object.Object{left}, args...))
+}
+
+// compose returns a builtin that calls first with its arguments and second
+// with the result.
+func compose(first, second object.Object) object.Object {
+	return &object.Builtin{
+		FrameFn: func(frame *object.Frame, args ...object.Object) (object.Object, error) {
+			result := applyCallback(frame, first, args...)
+			if err, ok := result.(*object.Error); ok {
+				return nil, err
+			}
+
+			result = applyCallback(frame, second, result)
+			if err, ok := result.(*object.Error); ok {
+				return nil, err
+			}
+
+			return result, nil
+		},
+	}
+}
+
 func evalStringInfixExpression(node *ast.InfixExpression, left, right object.Object) object.Object {
 	leftValue := left.(*object.String).Value
 	rightValue := right.(*object.String).Value
@@ -164,17 +299,118 @@ func evalStringInfixExpression(node *ast.InfixExpression, left, right object.Obj
 		return getBooleanObject(leftValue == rightValue)
 	case "!=":
 		return getBooleanObject(leftValue != rightValue)
+	case "<":
+		return getBooleanObject(leftValue < rightValue)
+	case ">":
+		return getBooleanObject(leftValue > rightValue)
 	default:
 		return newError(node.Line(), node.Column(), "unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
 	}
 }
 
+func evalSetInfixExpression(node *ast.InfixExpression, left, right object.Object) object.Object {
+	leftSet := left.(*object.Set)
+	rightSet := right.(*object.Set)
+	result := object.NewSet()
+
+	switch node.Operator {
+	case "|":
+		for key, element := range leftSet.Elements {
+			result.Elements[key] = element
+		}
+		for key, element := range rightSet.Elements {
+			result.Elements[key] = element
+		}
+	case "&":
+		for key, element := range leftSet.Elements {
+			if _, ok := rightSet.Elements[key]; ok {
+				result.Elements[key] = element
+			}
+		}
+	case "-":
+		for key, element := range leftSet.Elements {
+			if _, ok := rightSet.Elements[key]; !ok {
+				result.Elements[key] = element
+			}
+		}
+	case "==":
+		return getBooleanObject(object.Equal(left, right))
+	case "!=":
+		return getBooleanObject(!object.Equal(left, right))
+	default:
+		return newError(node.Line(), node.Column(), "unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
+	}
+
+	return result
+}
+
+func evalInExpression(node *ast.InfixExpression, left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Set:
+		hashable, ok := toHashable(left)
+		if !ok {
+			return getBooleanObject(false)
+		}
+		return getBooleanObject(right.Contains(hashable))
+	case *object.Hash:
+		hashable, ok := toHashable(left)
+		if !ok {
+			return getBooleanObject(false)
+		}
+		_, ok = right.Pairs[hashable.HashKey()]
+		return getBooleanObject(ok)
+	case *object.Dict:
+		hashable, ok := toHashable(left)
+		if !ok {
+			return getBooleanObject(false)
+		}
+		_, ok = right.Get(hashable.HashKey())
+		return getBooleanObject(ok)
+	case *object.Array:
+		return getBooleanObject(containsObject(right.Elements, left))
+	case *object.Vector:
+		return getBooleanObject(containsObject(right.Elements(), left))
+	case *object.Range:
+		integer, ok := left.(*object.Integer)
+		return getBooleanObject(ok && right.Contains(integer.Value))
+	case *object.Tuple:
+		return getBooleanObject(containsObject(right.Elements, left))
+	case *object.String:
+		substring, ok := left.(*object.String)
+		if !ok {
+			return newError(node.Left.Line(), node.Left.Column(), "type mismatch: %s in %s", left.Type(), right.Type())
+		}
+		return getBooleanObject(strings.Contains(right.Value, substring.Value))
+	default:
+		return newError(node.Line(), node.Column(), "unknown operator: %s in %s", left.Type(), right.Type())
+	}
+}
+
+// containsObject reports whether obj is structurally equal to one of elements.
+func containsObject(elements []object.Object, obj object.Object) bool {
+	for _, element := range elements {
+		if object.Equal(element, obj) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func evalStringIntegerInfixExpression(node *ast.InfixExpression, left, right object.Object) object.Object {
 	string := left.(*object.String).Value
 	integer := right.(*object.Integer).Value
 
 	switch node.Operator {
 	case "*":
+		if integer < 0 {
+			return newError(node.Line(), node.Column(), "cannot repeat string a negative number of times: %d", integer)
+		}
+
+		if len(string) > 0 && integer > MAX_STRING_LENGTH/int64(len(string)) {
+			return newError(node.Line(), node.Column(), "repeated string is too long, the limit is %d bytes", MAX_STRING_LENGTH)
+		}
+
 		return &object.String{Value: strings.Repeat(string, int(integer))}
 	default:
 		return newError(node.Line(), node.Column(), "unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
@@ -193,6 +429,9 @@ func evalIntegerInfixExpression(node *ast.InfixExpression, left, right object.Ob
 	case "*":
 		return &object.Integer{Value: leftValue * rightValue}
 	case "/":
+		if rightValue == 0 {
+			return newError(node.Right.Line(), node.Right.Column(), "division by zero")
+		}
 		return &object.Integer{Value: leftValue / rightValue}
 	case "<":
 		return getBooleanObject(leftValue < rightValue)
@@ -202,38 +441,120 @@ func evalIntegerInfixExpression(node *ast.InfixExpression, left, right object.Ob
 		return getBooleanObject(leftValue == rightValue)
 	case "!=":
 		return getBooleanObject(leftValue != rightValue)
+	case "..":
+		if rightValue == math.MaxInt64 {
+			return newError(node.Right.Line(), node.Right.Column(), "range end %d is too large", rightValue)
+		}
+		return evalRange(node, leftValue, rightValue+1)
+	case "..<":
+		return evalRange(node, leftValue, rightValue)
 	default:
 		return newError(node.Line(), node.Column(), "unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
 	}
 }
 
+// evalRange returns the range [start, stop). Ranges with more elements than
+// the largest integer are rejected, so that their length does not overflow.
+func evalRange(node *ast.InfixExpression, start, stop int64) object.Object {
+	if stop > start && stop-start < 0 {
+		return newError(node.Line(), node.Column(), "range %d..<%d has too many elements", start, stop)
+	}
+
+	return &object.Range{Start: start, Stop: stop}
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+	return evalIf(ie, env, evalBlockStatement)
+}
+
+// evalIf evaluates an if expression, using evalBlock to evaluate the branch
+// that is taken.
+func evalIf(ie *ast.IfExpression, env *object.Environment, evalBlock func(*ast.BlockStatement, *object.Environment) object.Object) object.Object {
+	condition := evalCondition(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
-	switch condition {
-	case TRUE:
-		value := Eval(ie.Consequence, env)
-		if isError(value) {
-			return value
-		}
-
-		return wrapMaybe(value)
-	case FALSE:
+	block := ie.Consequence
+	if condition == FALSE {
 		if ie.Alternative == nil {
 			return &EMPTY_MAYBE
 		}
 
-		value := Eval(ie.Alternative, env)
-		if isError(value) {
-			return value
-		}
+		block = ie.Alternative
+	}
 
-		return wrapMaybe(value)
+	value := evalBlock(block, env)
+	if isError(value) {
+		return value
+	}
+
+	return wrapMaybe(value)
+}
+
+// evalCondition evaluates the condition node to TRUE or FALSE according to the
+// condition mode of env.
+func evalCondition(node ast.Expression, env *object.Environment) object.Object {
+	condition := Eval(node, env)
+	if isError(condition) {
+		return condition
+	}
+
+	holds, ok := conditionHolds(condition, env.ConditionMode())
+	if !ok {
+		return newError(node.Line(), node.Column(), "condition must be BOOLEAN, got %s", condition.Type())
+	}
+
+	return getBooleanObject(holds)
+}
+
+// conditionHolds reports whether value holds as a condition in mode. ok is
+// false if mode does not accept value as a condition.
+func conditionHolds(value object.Object, mode object.ConditionMode) (holds, ok bool) {
+	if mode == object.TRUTHY_CONDITIONS {
+		return isTruthy(value), true
+	}
+
+	// Booleans of embedders are not necessarily TRUE or FALSE.
+	boolean, ok := value.(*object.Boolean)
+	if !ok {
+		return false, false
+	}
+
+	return boolean.Value, true
+}
+
+// isTruthy reports whether obj holds as a condition in truthy mode. Zero,
+// empty strings, empty collections and ranges, empty maybes and unit are
+// false. Every other value, including functions and sequences, is true.
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Integer:
+		return obj.Value != 0
+	case *object.String:
+		return obj.Value != ""
+	case *object.Array:
+		return len(obj.Elements) != 0
+	case *object.Tuple:
+		return len(obj.Elements) != 0
+	case *object.Hash:
+		return len(obj.Pairs) != 0
+	case *object.Set:
+		return len(obj.Elements) != 0
+	case *object.Vector:
+		return obj.Len() != 0
+	case *object.Dict:
+		return obj.Len() != 0
+	case *object.Range:
+		return obj.Len() != 0
+	case *object.Maybe:
+		return obj.Value != nil
+	case *object.Unit:
+		return false
 	default:
-		return &EMPTY_MAYBE
+		return true
 	}
 }
 
@@ -242,24 +563,27 @@ func wrapMaybe(value object.Object) object.Object {
 	case *object.ReturnValue:
 		obj.Value = wrapMaybe(obj.Value)
 		return obj
+	case *tailCall:
+		obj.wrap = true
+		return obj
 	case *object.Maybe:
 		return obj
+	case *object.Unit:
+		return &EMPTY_MAYBE
 	default:
 		return &object.Maybe{Value: value}
 	}
 }
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
-	var result object.Object
+	var result object.Object = UNIT
 
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJECT || rt == object.ERROR_OBJECT {
-				return result
-			}
+		rt := result.Type()
+		if rt == object.RETURN_VALUE_OBJECT || rt == object.ERROR_OBJECT {
+			return result
 		}
 	}
 
@@ -272,7 +596,53 @@ func evalLetStatement(node *ast.LetStatement, env *object.Environment) object.Ob
 		return value
 	}
 
-	env.Set(node.Identifier.Value, value)
+	if node.Identifier == nil {
+		return evalDestructuringLet(node, value, env)
+	}
+
+	if err := bind(node, node.Identifier, value, env); err != nil {
+		return err
+	}
+
+	return UNIT
+}
+
+func evalDestructuringLet(node *ast.LetStatement, value object.Object, env *object.Environment) object.Object {
+	tuple, ok := value.(*object.Tuple)
+	if !ok {
+		return newError(node.Value.Line(), node.Value.Column(), "cannot destructure %s, expected TUPLE", value.Type())
+	}
+
+	if len(tuple.Elements) != len(node.Names) {
+		return newError(node.Value.Line(), node.Value.Column(), "cannot destructure tuple of length %d into %d names", len(tuple.Elements), len(node.Names))
+	}
+
+	for i, name := range node.Names {
+		if err := bind(node, name, tuple.Elements[i], env); err != nil {
+			return err
+		}
+	}
+
+	return UNIT
+}
+
+// bind binds name to value for a let or const statement. Constants cannot be
+// rebound in the same environment and frozen environments accept no bindings.
+func bind(node *ast.LetStatement, name *ast.Identifier, value object.Object, env *object.Environment) object.Object {
+	if env.IsFrozen() {
+		return newError(name.Line(), name.Column(), "cannot assign %s, environment is frozen", name.Value)
+	}
+
+	if env.IsConstant(name.Value) {
+		return newError(name.Line(), name.Column(), "cannot reassign constant %s", name.Value)
+	}
+
+	if node.IsConstant() {
+		env.SetConstant(name.Value, value)
+	} else {
+		env.Set(name.Value, value)
+	}
+
 	return nil
 }
 
@@ -285,31 +655,86 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
-	return newError(node.Line(), node.Column(), "identifier not found: %s", node.Value)
+	return newError(node.Line(), node.Column(), "identifier not found: %s%s", node.Value, didYouMean(identifierSuggestions(node.Value, env)))
 }
 
 func evalFunction(node *ast.FunctionLiteral, env *object.Environment) object.Object {
 	return &object.Function{
-		Parameters: node.Parameters,
-		Env:        env,
-		Body:       node.Body,
+		Parameters:  node.Parameters,
+		Env:         env,
+		Body:        node.Body,
+		IsGenerator: node.IsGenerator,
 	}
 }
 
 func evalCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
-	function := Eval(node.Function, env)
-	if isError(function) {
-		return function
+	return resolveTailCall(evalTailCall(node, env))
+}
+
+// ApplyFunction calls a Monkey function or builtin with args. It is the entry
+// point for Go code that wants to call back into Monkey. The call starts a new
+// call stack.
+func ApplyFunction(function object.Object, args ...object.Object) object.Object {
+	return applyCallback(nil, function, args...)
+}
+
+// applyCallback calls function with args on top of frame, the call stack of
+// the builtin or composed function calling back into Monkey code. That way
+// recursion through callbacks counts towards the maximum call depth.
+func applyCallback(frame *object.Frame, function object.Object, args ...object.Object) object.Object {
+	line, column := 0, 0
+	if fn, ok := function.(*object.Function); ok {
+		line, column = fn.Body.Line(), fn.Body.Column()
 	}
 
-	args := evalExpressions(node.Arguments, env)
-	if len(args) == 1 && isError(args[0]) {
-		return args[0]
+	return applyFunction(frame.Push(ANONYMOUS_FUNCTION, line, column), function, args)
+}
+
+// applyFunction calls function with args. frame describes the call, its
+// position is used for errors that are not caused by a specific expression.
+//
+// Calls in tail position of a function body are not made by the body itself
+// but returned as a tail call, which is then made by the loop below. That way
+// tail-recursive functions run in constant Go stack.
+func applyFunction(frame *object.Frame, function object.Object, args []object.Object) object.Object {
+	wrap := false
+
+	for {
+		result := callFunction(frame, function, args)
+		if err, ok := result.(*object.Error); ok && err.Stack == nil {
+			// The innermost call the error passes through has the
+			// complete stack.
+			err.Stack = frame.Stack()
+		}
+
+		call, ok := result.(*tailCall)
+		if !ok {
+			if wrap && !isError(result) {
+				return wrapMaybe(result)
+			}
+			return result
+		}
+
+		// The tail call replaces the frame of the call that made it.
+		wrap = wrap || call.wrap
+		frame = frame.Parent.Push(call.name, call.line, call.column)
+		function, args = call.function, call.args
 	}
+}
+
+// callFunction calls function with args. Calls in tail position of the body
+// of a Monkey function are returned as *tailCall.
+func callFunction(frame *object.Frame, function object.Object, args []object.Object) object.Object {
+	line, column := frame.Line, frame.Column
 
 	switch fn := function.(type) {
 	case *object.Function:
-		extendedEnv := object.NewEnclosedEnvironment(fn.Env)
+		if frame.Depth > maxCallDepth(fn.Env) {
+			return newRecursionError(frame)
+		}
+
+		extendedEnv := object.NewCallEnvironment(fn.Env)
+		extendedEnv.SetFrame(frame)
 		if len(args) < len(fn.Parameters) {
 			missingParameters := []string{}
 
@@ -317,22 +742,45 @@ func evalCallExpression(node *ast.CallExpression, env *object.Environment) objec
 				missingParameters = append(missingParameters, fn.Parameters[i].Value)
 			}
 
-			return newError(node.Line(), node.Column(), "missing parameters %q in function call", strings.Join(missingParameters, ", "))
+			return newError(line, column, "missing parameters %q in function call", strings.Join(missingParameters, ", "))
 		}
 		for paramIdx, param := range fn.Parameters {
-			env.Set(param.Value, args[paramIdx])
+			extendedEnv.Set(param.Value, args[paramIdx])
+		}
+		if fn.IsGenerator {
+			return newGenerator(fn, extendedEnv)
+		}
+		evaluated := unwrapReturnValue(evalTailBlock(fn.Body, extendedEnv))
+		if deferred := extendedEnv.Deferred(); len(deferred) != 0 {
+			// Deferred code runs after a call in tail position returns, so
+			// the call is made here instead of by the trampoline.
+			evaluated = runDeferred(deferred, resolveTailCall(evaluated))
 		}
-		evaluated := Eval(fn.Body, extendedEnv)
 
-		return unwrapReturnValue(evaluated)
+		return evaluated
 	case *object.Builtin:
-		result, err := fn.Fn(args...)
+		var result object.Object
+		var err error
+		if fn.FrameFn != nil {
+			result, err = fn.FrameFn(frame, args...)
+		} else {
+			result, err = fn.Fn(args...)
+		}
+		if errObj, ok := err.(*object.Error); ok {
+			// Errors raised by Monkey code called from the builtin, e.g. by
+			// a lazy map, already carry their own position.
+			return errObj
+		}
 		if err != nil {
-			return newError(node.Line(), node.Column(), err.Error())
+			return newError(line, column, "%s", err.Error())
+		}
+		if result == nil {
+			// Builtins of embedders may return nil for no value.
+			return UNIT
 		}
 		return result
 	default:
-		return newError(node.Line(), node.Column(), "not a function: %s", function)
+		return newError(line, column, "not a function: %s", function.Type())
 	}
 }
 
@@ -342,6 +790,34 @@ func evalArray(node *ast.ArrayLiteral, env *object.Environment) object.Object {
 	return obj
 }
 
+func evalTuple(node *ast.TupleLiteral, env *object.Environment) object.Object {
+	elements := evalExpressions(node.Elements, env)
+	if len(elements) == 1 && isError(elements[0]) {
+		return elements[0]
+	}
+
+	return &object.Tuple{Elements: elements}
+}
+
+func evalSetLiteral(node *ast.SetLiteral, env *object.Environment) object.Object {
+	set := object.NewSet()
+
+	for _, element := range node.Elements {
+		obj := Eval(element, env)
+		if isError(obj) {
+			return obj
+		}
+
+		if _, ok := toHashable(obj); !ok {
+			return newError(element.Line(), element.Column(), "cannot use type %s as element of set", obj.Type())
+		}
+
+		set.Add(obj)
+	}
+
+	return set
+}
+
 func evalIndexExpression(node *ast.IndexExpression, env *object.Environment) object.Object {
 	left := Eval(node.Left, env)
 	if isError(left) {
@@ -359,15 +835,71 @@ func evalIndexExpression(node *ast.IndexExpression, env *object.Environment) obj
 			return newError(node.Index.Line(), node.Index.Column(), "cannot use %s as index for array", index.Type())
 		}
 		arrObj := left.(*object.Array)
-		idxValue := index.(*object.Integer).Value
-
-		if int(idxValue) < 0 || int(idxValue) >= len(arrObj.Elements) {
+		idxValue, ok := normalizeIndex(index.(*object.Integer).Value, len(arrObj.Elements))
+		if !ok {
 			return &EMPTY_MAYBE
 		}
 
 		return wrapMaybe(arrObj.Elements[idxValue])
+	case object.TUPLE_OBJECT:
+		if index.Type() != object.INTEGER_OBJECT {
+			return newError(node.Index.Line(), node.Index.Column(), "cannot use %s as index for tuple", index.Type())
+		}
+		tuple := left.(*object.Tuple)
+		idxValue, ok := normalizeIndex(index.(*object.Integer).Value, len(tuple.Elements))
+		if !ok {
+			return newError(node.Index.Line(), node.Index.Column(), "index %d out of range for tuple of length %d", index.(*object.Integer).Value, len(tuple.Elements))
+		}
+
+		return tuple.Elements[idxValue]
+	case object.VECTOR_OBJECT:
+		if index.Type() != object.INTEGER_OBJECT {
+			return newError(node.Index.Line(), node.Index.Column(), "cannot use %s as index for vector", index.Type())
+		}
+		vector := left.(*object.Vector)
+		idxValue, ok := normalizeIndex(index.(*object.Integer).Value, vector.Len())
+		if !ok {
+			return &EMPTY_MAYBE
+		}
+
+		return wrapMaybe(vector.Get(idxValue))
+	case object.STRING_OBJECT:
+		if index.Type() != object.INTEGER_OBJECT {
+			return newError(node.Index.Line(), node.Index.Column(), "cannot use %s as index for string", index.Type())
+		}
+		// Strings are indexed by character, not by byte.
+		runes := []rune(left.(*object.String).Value)
+		idxValue, ok := normalizeIndex(index.(*object.Integer).Value, len(runes))
+		if !ok {
+			return &EMPTY_MAYBE
+		}
+
+		return wrapMaybe(&object.String{Value: string(runes[idxValue])})
+	case object.RANGE_OBJECT:
+		if index.Type() != object.INTEGER_OBJECT {
+			return newError(node.Index.Line(), node.Index.Column(), "cannot use %s as index for range", index.Type())
+		}
+		rangeObj := left.(*object.Range)
+		idxValue, ok := normalizeIndex(index.(*object.Integer).Value, rangeObj.Len())
+		if !ok {
+			return &EMPTY_MAYBE
+		}
+
+		return wrapMaybe(rangeObj.Get(idxValue))
+	case object.DICT_OBJECT:
+		hashableIndex, ok := toHashable(index)
+		if !ok {
+			return newError(node.Index.Line(), node.Index.Column(), "can not use index of type %s for dict", index.Type())
+		}
+
+		pair, ok := left.(*object.Dict).Get(hashableIndex.HashKey())
+		if !ok {
+			return &EMPTY_MAYBE
+		}
+
+		return wrapMaybe(pair.Value)
 	case object.HASH_OBJECT:
-		hashableIndex, ok := index.(object.Hashable)
+		hashableIndex, ok := toHashable(index)
 		if !ok {
 			return newError(node.Index.Line(), node.Index.Column(), "can not use index of type %s for hash", index.Type())
 		}
@@ -384,31 +916,129 @@ func evalIndexExpression(node *ast.IndexExpression, env *object.Environment) obj
 	}
 }
 
+// normalizeIndex resolves negative indices relative to the end of a sequence
+// of the given length and reports whether the index is in range.
+func normalizeIndex(index int64, length int) (int, bool) {
+	if index < 0 {
+		index += int64(length)
+	}
+
+	if index < 0 || index >= int64(length) {
+		return 0, false
+	}
+
+	return int(index), true
+}
+
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var length int
+	var runes []rune
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.Tuple:
+		length = len(left.Elements)
+	case *object.String:
+		runes = []rune(left.Value)
+		length = len(runes)
+	case *object.Vector:
+		length = left.Len()
+	case *object.Range:
+		length = left.Len()
+	default:
+		return newError(node.Line(), node.Column(), "cannot slice %s", left.Type())
+	}
+
+	start, err := evalSliceBound(node.Start, 0, length, env)
+	if err != nil {
+		return err
+	}
+
+	end, err := evalSliceBound(node.Stop, length, length, env)
+	if err != nil {
+		return err
+	}
+
+	if end < start {
+		end = start
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		return &object.Array{Elements: append([]object.Object{}, left.Elements[start:end]...)}
+	case *object.Tuple:
+		return &object.Tuple{Elements: append([]object.Object{}, left.Elements[start:end]...)}
+	case *object.String:
+		return &object.String{Value: string(runes[start:end])}
+	case *object.Vector:
+		return object.NewVector(left.Elements()[start:end]...)
+	default:
+		rangeObj := left.(*object.Range)
+		return &object.Range{Start: rangeObj.Start + int64(start), Stop: rangeObj.Start + int64(end)}
+	}
+}
+
+// evalSliceBound evaluates an optional slice bound. Negative bounds count from
+// the end and out of range bounds are clamped to the sequence.
+func evalSliceBound(bound ast.Expression, fallback, length int, env *object.Environment) (int, *object.Error) {
+	if bound == nil {
+		return fallback, nil
+	}
+
+	value := Eval(bound, env)
+	if isError(value) {
+		return 0, value.(*object.Error)
+	}
+
+	integer, ok := value.(*object.Integer)
+	if !ok {
+		return 0, newError(bound.Line(), bound.Column(), "cannot use %s as slice bound", value.Type())
+	}
+
+	index := integer.Value
+	if index < 0 {
+		index += int64(length)
+	}
+
+	switch {
+	case index < 0:
+		return 0, nil
+	case index > int64(length):
+		return length, nil
+	default:
+		return int(index), nil
+	}
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for key, value := range node.Pairs {
-		keyObj := Eval(key, env)
+	for _, pair := range node.Pairs {
+		keyObj := Eval(pair.Key, env)
 
 		if isError(keyObj) {
 			return keyObj
 		}
 
-		hashableKey, ok := keyObj.(object.Hashable)
+		hashableKey, ok := toHashable(keyObj)
 		if !ok {
-			return newError(value.Line(), value.Column(), "cannot use type %s as key for hash", keyObj.Type())
+			return newError(pair.Value.Line(), pair.Value.Column(), "cannot use type %s as key for hash", keyObj.Type())
 		}
 
-		valueObj := Eval(value, env)
+		valueObj := Eval(pair.Value, env)
 		if isError(valueObj) {
 			return valueObj
 		}
 
-		hashed := hashableKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: keyObj, Value: valueObj}
+		hash.Set(hashableKey.HashKey(), object.HashPair{Key: keyObj, Value: valueObj})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
@@ -425,27 +1055,40 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) []ob
 
 	return result
 }
+
+// property evaluates a property of subject.
+type property func(prop *ast.PropertyExpression, subject object.Object) object.Object
+
+// properties holds the properties of objects by type. Unknown properties are
+// matched against it for suggestions.
+var properties = map[object.ObjectType]map[string]property{
+	object.MAYBE_OBJECT: {
+		"hasValue": func(prop *ast.PropertyExpression, subject object.Object) object.Object {
+			return getBooleanObject(subject.(*object.Maybe).Value != nil)
+		},
+		"value": func(prop *ast.PropertyExpression, subject object.Object) object.Object {
+			value := subject.(*object.Maybe).Value
+			if value == nil {
+				return newError(prop.Line(), prop.Column(), "%q has no value! check before with \"hasValue\"!", prop.String())
+			}
+
+			return value
+		},
+	},
+}
+
 func evalPropertyExpression(prop *ast.PropertyExpression, env *object.Environment) object.Object {
 	subject := Eval(prop.Subject, env)
 	if isError(subject) {
 		return subject
 	}
 
-	switch sub := subject.(type) {
-	case *object.Maybe:
-		switch prop.Name.Value {
-		case "hasValue":
-			return getBooleanObject(sub.Value != nil)
-		case "value":
-			if sub.Value == nil {
-				return newError(prop.Line(), prop.Column(), "%q has no value! check before with \"hasValue\"!", prop.String())
-			}
-
-			return sub.Value
-		}
+	if property, ok := properties[subject.Type()][prop.Name.Value]; ok {
+		return property(prop, subject)
 	}
 
-	return newError(prop.Line(), prop.Column(), "%s has no property %q.", subject.Type(), prop.Name.TokenLiteral())
+	name := prop.Name.TokenLiteral()
+	return newError(prop.Line(), prop.Column(), "%s has no property %q%s.", subject.Type(), name, didYouMean(propertySuggestions(name, subject)))
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -472,6 +1115,21 @@ func newError(line, column int, format string, a ...interface{}) *object.Error {
 	}
 }
 
+// toHashable returns obj as object.Hashable if it can be used as a hash key.
+// Tuples are only hashable if all of their elements are.
+func toHashable(obj object.Object) (object.Hashable, bool) {
+	if tuple, ok := obj.(*object.Tuple); ok {
+		for _, element := range tuple.Elements {
+			if _, ok := toHashable(element); !ok {
+				return nil, false
+			}
+		}
+	}
+
+	hashable, ok := obj.(object.Hashable)
+	return hashable, ok
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJECT
@@ -3,11 +3,16 @@ package evaluator
 import (
 	"errors"
 	"fmt"
+	"github.com/hendrikbursian/monkey-programming-language/ast"
 	"github.com/hendrikbursian/monkey-programming-language/lexer"
 	"github.com/hendrikbursian/monkey-programming-language/object"
 	"github.com/hendrikbursian/monkey-programming-language/parser"
+	"reflect"
+	"runtime"
+	"runtime/debug"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -71,6 +76,64 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[1, 2] == [1, 2]`, true},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1, [2, "a"]] != [1, [2, "a"]]`, false},
+		{`(1, "a") == (1, "a")`, true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{1, 2} == {2, 1}`, true},
+		{`{1, 2} != {1}`, true},
+		{`first([1]) == first([1])`, true},
+		{`first([1]) == first([2])`, false},
+		{`[][0] == [][1]`, true},
+		{`let f = fn(x) { x }; f == f`, true},
+		{`fn(x) { x } == fn(x) { x }`, false},
+		{`l == l`, true},
+		{`[1] == (1,)`, false},
+		{`1 == "1"`, false},
+		{`let a = [1]; push(a, a); let b = [1]; push(b, b); a == b`, true},
+		{`let a = [1]; push(a, a); let b = [2]; push(b, b); a == b`, false},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestOrdering(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"ab" > "a"`, true},
+		{`[1, 2] < [1, 3]`, true},
+		{`[1, 2] < [1, 2, 0]`, true},
+		{`[2] > [1, 5]`, true},
+		{`(1, "b") > (1, "a")`, true},
+		{`false < true`, true},
+		{`[1, "a"] < [1, 2]`, errors.New("cannot compare ARRAY < ARRAY")},
+		{`{"a": 1} < {"a": 2}`, errors.New("cannot compare HASH < HASH")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -204,6 +267,11 @@ if (10 > 1) {
 			"identifier not found: foobar",
 			1, 1,
 		},
+		{
+			"let x = 5; x(1)",
+			"not a function: INTEGER",
+			1, 12,
+		},
 		{
 			`
 let test = fn(x, y, z){return x*y*z};
@@ -218,6 +286,11 @@ test(3)`,
 			"missing parameters \"y\" in function call",
 			3, 1,
 		},
+		{
+			"let x = 1; !x;",
+			"condition must be BOOLEAN, got INTEGER",
+			1, 13,
+		},
 	}
 
 	for i, test := range tests {
@@ -264,6 +337,63 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const a = 5; a;", 5},
+		{"const a = 5; let a = 6;", errors.New("cannot reassign constant a")},
+		{"const a = 5; const a = 6;", errors.New("cannot reassign constant a")},
+		{"const (a, b) = (1, 2); let b = 3;", errors.New("cannot reassign constant b")},
+		{"let a = 5; const a = 6; a", 6},
+		{"const a = 5; let f = fn() { let a = 6; a }; f() + a", 11},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = freeze([1]); push(a, 2)", errors.New("cannot push to frozen array")},
+		{"let a = [1]; freeze((a,)); push(a, 2)", errors.New("cannot push to frozen array")},
+		{"let a = [1]; freeze({\"a\": [a]}); push(a, 2)", errors.New("cannot push to frozen array")},
+		{"let a = [1]; push(a, a); freeze(a); l(a)", 2},
+		{"let a = [1]; let b = freeze([a]); push(a, 2)", errors.New("cannot push to frozen array")},
+		{"let a = [1]; freeze(a) == a", true},
+		{"let a = [1]; push(a, 2)", []interface{}{1, 2}},
+		{"let a = [1]; let f = fn() { push(a, 2) }; freeze(f); f()", errors.New("cannot push to frozen array")},
+		{"let f = fn() { let a = [1]; fn(x) { push(a, x) } }; let g = freeze(f()); g(2)", errors.New("cannot push to frozen array")},
+		{"let f = fn() { f }; freeze(f); let a = 1; a", 1},
+		{"let a = [1]; let f = fn() { push(a, 2) } >> fn(x) { x }; freeze(f); f()", []interface{}{1, 2}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+
+	evaluated := testEval("let a = freeze([1]);\nlet b = 2; push(a, b)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("evaluated is not an error. got=%T", evaluated)
+	}
+
+	if errObj.Line != 2 || errObj.Column != 12 {
+		t.Errorf("wrong error position. want=2:12, got=%d:%d", errObj.Line, errObj.Column)
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -298,6 +428,7 @@ func TestFunctionApplication(t *testing.T) {
 		{"let add = fn(x, y){x+y}; add(5, 3)", 8},
 		{"let add = fn(x, y){x+y}; add(add(5, 3), 5+5)", 18},
 		{"fn(x){x;}(5)", 5},
+		{"let x = 1; let f = fn(x){x;}; f(2); x", 1},
 	}
 
 	for i, test := range tests {
@@ -331,6 +462,8 @@ func TestEvalStringLiteral(t *testing.T) {
 		{"\"helloworld\"", "helloworld"},
 		{"\"hello\" + \" \" + \"world\"", "hello world"},
 		{"\"hello\" * 3", "hellohellohello"},
+		{"\"hello\" * 0", ""},
+		{"\"\" * 9223372036854775807", ""},
 	}
 
 	for i, test := range tests {
@@ -377,6 +510,57 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestPersistentCollections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`vector()`, "vector([])"},
+		{`vector([1, 2, 3])`, "vector([1, 2, 3])"},
+		{`let v = vector([1]); let w = conj(v, 2); (v, w)`, "(vector([1]), vector([1, 2]))"},
+		{`let v = vector([1, 2]); let w = assoc(v, 0, "a"); (v, w)`, `(vector([1, 2]), vector(["a", 2]))`},
+		{`assoc(vector([1]), 1, 2)`, "vector([1, 2])"},
+		{`assoc(vector([1]), 3, 2)`, errors.New("index 3 out of range for vector of length 1")},
+		{`rest(vector([1, 2, 3]))`, "vector([2, 3])"},
+		{`rest(vector())`, "vector([])"},
+		{`rest([1, 2, 3])`, "[2, 3]"},
+		{`vector([1, 2])[1]`, Maybe{2}},
+		{`vector([1, 2])[2]`, Maybe{nil}},
+		{`first(rest(vector([1, 2])))`, Maybe{2}},
+		{`last(vector([1, 2]))`, Maybe{2}},
+		{`first(vector())`, Maybe{nil}},
+		{`l(conj(vector([1]), 2))`, 2},
+		{`array(vector([1, 2]))`, []interface{}{1, 2}},
+		{`2 in vector([1, 2])`, true},
+		{`vector([1, [2]]) == vector([1, [2]])`, true},
+		{`vector([1]) < vector([2])`, true},
+		{`dict()`, "dict({})"},
+		{`let d = dict({"a": 1}); let e = assoc(d, "b", 2); (d["b"], e["b"])`, "(maybe([no value]), maybe(2))"},
+		{`let d = dict({"a": 1}); let e = dissoc(d, "a"); (l(d), l(e))`, "(1, 0)"},
+		{`"a" in dict({"a": 1})`, true},
+		{`assoc(dict(), [1], 1)`, errors.New("cannot use type ARRAY as key for dict")},
+		{`dict({"a": 1, "b": 2}) == assoc(assoc(dict(), "b", 2), "a", 1)`, true},
+		{`conj([1], 2)`, errors.New("first argument to conj has to be a vector, got ARRAY instead")},
+		{`let build = fn(v, n) { if n == 0 { return v }; build(conj(v, n), n - 1) }; l(build(vector(), 3000).value)`, 3000},
+		{`let sum = fn(v) { if l(v) == 0 { 0 } else { first(v).value + sum(rest(v)).value } }; sum(vector([1, 2, 3, 4]))`, Maybe{10}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+
+			if inspected, ok := tt.expected.(string); ok {
+				if evaluated.Inspect() != inspected {
+					t.Errorf("evaluated.Inspect() wrong. want=%q, got=%q", inspected, evaluated.Inspect())
+				}
+				return
+			}
+
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
 func TestArrayLiteral(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -490,6 +674,756 @@ func TestEvalIndexExpression(t *testing.T) {
 	}
 }
 
+func TestSlicesAndRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[1, 2, 3][-1]`, Maybe{3}},
+		{`[1, 2, 3][-3]`, Maybe{1}},
+		{`[1, 2, 3][-4]`, Maybe{nil}},
+		{`(1, 2, 3)[-1]`, 3},
+		{`(1, 2)[-3]`, errors.New("index -3 out of range for tuple of length 2")},
+		{`"hello"[1]`, Maybe{"e"}},
+		{`"hello"[-1]`, Maybe{"o"}},
+		{`"hello"[5]`, Maybe{nil}},
+		{`"héllo"[1]`, Maybe{"é"}},
+		{`"héllo"[-4]`, Maybe{"é"}},
+		{`"héllo"[1:3]`, "él"},
+		{`l("héllo")`, 5},
+		{`array("hé")`, []interface{}{"h", "é"}},
+		{`[1, 2, 3, 4][1:3]`, []interface{}{2, 3}},
+		{`[1, 2, 3, 4][:-1]`, []interface{}{1, 2, 3}},
+		{`[1, 2, 3, 4][2:]`, []interface{}{3, 4}},
+		{`[1, 2, 3, 4][:]`, []interface{}{1, 2, 3, 4}},
+		{`[1, 2, 3, 4][3:1]`, []interface{}{}},
+		{`[1, 2, 3, 4][-10:10]`, []interface{}{1, 2, 3, 4}},
+		{`let a = [1, 2]; let b = a[:]; push(b, 3); a`, []interface{}{1, 2}},
+		{`"hello"[2:]`, "llo"},
+		{`"hello"[:-2]`, "hel"},
+		{`(1, 2, 3)[1:]`, Tuple{2, 3}},
+		{`vector([1, 2, 3])[1:]`, "vector([2, 3])"},
+		{`[1, 2]["a":]`, errors.New("cannot use STRING as slice bound")},
+		{`5[1:]`, errors.New("cannot slice INTEGER")},
+		{`1..5`, "1..<6"},
+		{`1..<5`, "1..<5"},
+		{`l(1..10)`, 10},
+		{`l(5..1)`, 0},
+		{`array(1..<4)`, []interface{}{1, 2, 3}},
+		{`(1..10)[0]`, Maybe{1}},
+		{`(1..10)[-1]`, Maybe{10}},
+		{`(1..10)[10]`, Maybe{nil}},
+		{`(1..10)[2:4]`, "3..<5"},
+		{`(0..<1000000000)[999999999]`, Maybe{999999999}},
+		{`5 in 1..10`, true},
+		{`10 in 1..<10`, false},
+		{`"a" in 1..10`, false},
+		{`1..3 == 1..<4`, true},
+		{`5..1 == 3..<3`, true},
+		{`let n = 3; set(0..n)`, "{0, 1, 2, 3}"},
+		{`"a".."b"`, errors.New("unknown operator: STRING .. STRING")},
+		{`l(1..9223372036854775807)`, errors.New("range end 9223372036854775807 is too large")},
+		{`l(0..<9223372036854775807)`, 9223372036854775807},
+		{`(0..9223372036854775806)[9223372036854775806]`, Maybe{9223372036854775806}},
+		{`l(-1..<9223372036854775807)`, errors.New("range -1..<9223372036854775807 has too many elements")},
+		{`l(-5..9223372036854775806)`, errors.New("range -5..<9223372036854775807 has too many elements")},
+		{`l(9223372036854775806..-9223372036854775807)`, 0},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+
+			if inspected, ok := tt.expected.(string); ok && evaluated.Type() != object.STRING_OBJECT {
+				if evaluated.Inspect() != inspected {
+					t.Errorf("evaluated.Inspect() wrong. want=%q, got=%q", inspected, evaluated.Inspect())
+				}
+				return
+			}
+
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestForLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let out = []; for x in [1, 2, 3] { push(out, x * 2) }; out`, []interface{}{2, 4, 6}},
+		{`let out = []; for c in "abc" { push(out, c) }; out`, []interface{}{"a", "b", "c"}},
+		{`let out = []; for k in {"b": 1, "a": 2} { push(out, k) }; out`, []interface{}{"b", "a"}},
+		{`let out = []; for x in 1..<4 { push(out, x) }; out`, []interface{}{1, 2, 3}},
+		{`let out = []; for x in {3, 1, 2} { push(out, x) }; out`, []interface{}{1, 2, 3}},
+		{`let out = []; for x in vector([1, 2]) { push(out, x) }; out`, []interface{}{1, 2}},
+		{`let out = []; for (a, b) in [(1, 2), (3, 4)] { push(out, a + b) }; out`, []interface{}{3, 7}},
+		{`let f = fn() { for x in [1, 2, 3] { if x == 2 { return x } } }; f()`, Maybe{2}},
+		{`for x in [1] { let y = x }; y`, errors.New("identifier not found: y")},
+		{`for x in 5 { x }`, errors.New("cannot iterate over INTEGER")},
+		{`for (a, b) in [1] { a }`, errors.New("cannot destructure INTEGER, expected TUPLE")},
+		{`for x in [1, 2] { x + true }`, errors.New("type mismatch: INTEGER + BOOLEAN")},
+		{`
+		let seen = [];
+		let counter = {"next": fn() { if l(seen) < 3 { push(seen, 0); l(seen) } }};
+		let out = [];
+		for x in counter { push(out, x * 10) };
+		out
+		`, []interface{}{10, 20, 30}},
+		{`array(take({"next": fn() { if true { 7 } }}, 2))`, []interface{}{7, 7}},
+		{`let it = {"next": fn() { 1 }}; for x in it { x }`, errors.New("next has to return a MAYBE, got INTEGER instead")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestLazySequences(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`array(map([1, 2, 3], fn(x) { x * 2 }))`, []interface{}{2, 4, 6}},
+		{`array(filter(1..6, fn(x) { x > 3 }))`, []interface{}{4, 5, 6}},
+		{`array(filter(1..6, fn(x) { if x > 4 { true } else { false } }))`, errors.New("predicate has to return a BOOLEAN, got MAYBE instead")},
+		{`array(take(0..<1000000000, 3))`, []interface{}{0, 1, 2}},
+		{`array(zip([1, 2, 3], "ab"))`, []interface{}{Tuple{1, "a"}, Tuple{2, "b"}}},
+		{`array(take(map(filter(0..<1000000000, fn(x) { x > 10 }), fn(x) { x * x }), 2))`, []interface{}{121, 144}},
+		{`let calls = []; let s = map([1, 2, 3], fn(x) { push(calls, x); x }); l(calls)`, 0},
+		{`let calls = []; let s = map([1, 2, 3], fn(x) { push(calls, x); x }); next(s); calls`, []interface{}{1}},
+		{`let s = iter([1, 2]); next(s); next(s)`, Maybe{2}},
+		{`let s = iter([1]); next(s); next(s)`, Maybe{nil}},
+		{`let s = iter([1, 2, 3]); next(s); array(s)`, []interface{}{2, 3}},
+		{`set(map([1, 2, 1], fn(x) { x }))`, "{1, 2}"},
+		{`vector(take(1..10, 2))`, "vector([1, 2])"},
+		{`map([1], fn(x) { x })`, "sequence"},
+		{`array(map([1, 2], fn(x) { x + true }))`, errors.New("type mismatch: INTEGER + BOOLEAN")},
+		{`map(5, fn(x) { x })`, errors.New("first argument to map has to be iterable, got INTEGER instead")},
+		{`map([1], 5)`, errors.New("second argument to map has to be a function, got INTEGER instead")},
+		{`array(5)`, errors.New("argument to array has to be iterable, got INTEGER instead")},
+		{`next([1])`, errors.New("first argument to next has to be a sequence, got ARRAY instead")},
+		{`zip([1])`, errors.New("wrong number of arguments to zip, got=1, want at least 2")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+
+			if inspected, ok := tt.expected.(string); ok && evaluated.Type() != object.STRING_OBJECT {
+				if evaluated.Inspect() != inspected {
+					t.Errorf("evaluated.Inspect() wrong. want=%q, got=%q", inspected, evaluated.Inspect())
+				}
+				return
+			}
+
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestGeneratorAlreadyRunning(t *testing.T) {
+	tests := []struct {
+		input  string
+		line   int
+		column int
+	}{
+		{`let g = fn*() { yield next(it); }; let it = g(); next(it)`, 1, 23},
+		{"let g = fn*() {\n  for x in it { yield x }\n}; let it = g(); next(it)", 2, 12},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			err, ok := testEval(tt.input).(*object.Error)
+			if !ok {
+				t.Fatalf("expected error, got=%T", err)
+			}
+
+			if err.Line != tt.line || err.Column != tt.column {
+				t.Errorf("wrong position. want=%d:%d, got=%d:%d", tt.line, tt.column, err.Line, err.Column)
+			}
+		})
+	}
+}
+
+func TestYieldInFunctionCalledByGenerator(t *testing.T) {
+	program := parser.New(lexer.New(`let g = fn*() { let f = fn() { 0 }; f(); yield 1 }; array(g())`)).ParseProgram()
+
+	// The parser rejects yield outside of fn*, so the yield is put into f
+	// by hand.
+	generator := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	function := generator.Body.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	statement := function.Body.Statements[0].(*ast.ExpressionStatement)
+	function.Body.Statements[0] = &ast.YieldStatement{Token: statement.Token, Value: statement.Expression}
+
+	result := Eval(program, object.NewEnvironment())
+	err, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("expected error, got=%s", result.Inspect())
+	}
+
+	if err.Message != "yield outside of generator function" {
+		t.Errorf("wrong error message. got=%q", err.Message)
+	}
+
+	if err.Line != 1 || err.Column != 32 {
+		t.Errorf("wrong position. want=1:32, got=%d:%d", err.Line, err.Column)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let squares = fn*(n) { for i in 1..n { yield i * i } }; array(squares(3))`, []interface{}{1, 4, 9}},
+		{`let naturals = fn*() { for i in 0..<1000000000000 { yield i } }; array(take(naturals(), 3))`, []interface{}{0, 1, 2}},
+		{`let g = fn*() { yield 1; return 5; yield 2 }; array(g())`, []interface{}{1}},
+		{`let g = fn*() { yield 1; if true { return 5 }; yield 2 }; array(g())`, []interface{}{1}},
+		{`let g = fn*() { yield 1; 1 + true }; array(g())`, errors.New("type mismatch: INTEGER + BOOLEAN")},
+		{`let g = fn*() { yield 1 }(); next(g); next(g)`, Maybe{nil}},
+		{`let g = fn*(a, b) { yield a; yield b }("x", "y"); next(g); next(g)`, Maybe{"y"}},
+		{`let calls = []; let g = fn*() { push(calls, 1); yield 1; push(calls, 2); yield 2 }(); next(g); calls`, []interface{}{1}},
+		{`let calls = []; let g = fn*() { push(calls, 1); yield 1 }(); calls`, []interface{}{}},
+		{`let g = fn*() { for x in fn*() { yield 1; yield 2 }() { yield x * 10 } }; array(g())`, []interface{}{10, 20}},
+		{`let out = []; for x in fn*() { yield "a"; yield "b" }() { push(out, x) }; out`, []interface{}{"a", "b"}},
+		{`array(map(fn*() { yield 1; yield 2 }(), fn(x) { x + 1 }))`, []interface{}{2, 3}},
+		{`fn*(x) { yield x }`, "fn*(x) {\n{ yield x; }\n}"},
+		{`let g = fn*() { yield next(it); }; let it = g(); next(it)`, errors.New("generator already running")},
+		{`let g = fn*() { for x in it { yield x } }; let it = g(); array(it)`, errors.New("generator already running")},
+		{`let g = fn*() { yield 1; yield 2 }; let it = g(); next(it); next(it)`, Maybe{2}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+
+			if inspected, ok := tt.expected.(string); ok && evaluated.Type() != object.STRING_OBJECT {
+				if evaluated.Inspect() != inspected {
+					t.Errorf("evaluated.Inspect() wrong. want=%q, got=%q", inspected, evaluated.Inspect())
+				}
+				return
+			}
+
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestAbandonedGeneratorsStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		testEval(`let naturals = fn*() { for i in 0..<1000000000000 { yield i } }; array(take(naturals(), 3))`)
+	}
+
+	for attempt := 0; attempt < 100 && runtime.NumGoroutine() > before; attempt++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if runtime.NumGoroutine() > before {
+		t.Errorf("abandoned generators are still running. goroutines before=%d, after=%d", before, runtime.NumGoroutine())
+	}
+}
+
+func TestAbandonedGeneratorsSkipDeferred(t *testing.T) {
+	env := object.NewEnvironment()
+	eval := func(input string) object.Object {
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+
+	before := runtime.NumGoroutine()
+	eval(`let shared = []; let g = fn*() { defer push(shared, 1); yield 1; yield 2 }; next(g());`)
+
+	// Deferred code running on the abandoned generator's goroutine would
+	// race with this evaluation, which go test -race reports.
+	for attempt := 0; attempt < 100 && runtime.NumGoroutine() > before; attempt++ {
+		runtime.GC()
+		eval(`l(shared)`)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if runtime.NumGoroutine() > before {
+		t.Fatalf("abandoned generator is still running. goroutines before=%d, after=%d", before, runtime.NumGoroutine())
+	}
+
+	testObjects(t, eval(`shared`), []interface{}{})
+}
+
+func TestComprehensions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[x * 2 for x in [1, -2, 3]]`, []interface{}{2, -4, 6}},
+		{`[x * 2 for x in [1, -2, 3] if x > 0]`, []interface{}{2, 6}},
+		{`[x for x in []]`, []interface{}{}},
+		{`[c for c in "abc" if c != "b"]`, []interface{}{"a", "c"}},
+		{`[a + b for (a, b) in zip(1..3, [10, 20, 30])]`, []interface{}{11, 22, 33}},
+		{`[[y * x for y in 1..2] for x in 1..2]`, "[[1, 2], [2, 4]]"},
+		{`let fs = [fn() { x } for x in 1..3]; [f() for f in fs]`, []interface{}{1, 2, 3}},
+		{`[x for x in 1..3]; x`, errors.New("identifier not found: x")},
+		{`{k: v * 10 for (k, v) in pairs({"a": 1, "b": 2})}`, `{"a": 10, "b": 20}`},
+		{`{v: k for (k, v) in pairs({"a": 1, "b": 2}) if v > 1}`, `{2: "b"}`},
+		{`{x: x * x for x in 1..3}`, `{1: 1, 2: 4, 3: 9}`},
+		{`pairs({"b": 1, "a": 2})`, `[("b", 1), ("a", 2)]`},
+		{`pairs(dict({"a": 1}))`, `[("a", 1)]`},
+		{`pairs([1])`, errors.New("argument to pairs has to be a hash or dict, got ARRAY instead")},
+		{`[x for x in 5]`, errors.New("cannot iterate over INTEGER")},
+		{`[x + true for x in [1]]`, errors.New("type mismatch: INTEGER + BOOLEAN")},
+		{`[x for x in [1] if x + true]`, errors.New("type mismatch: INTEGER + BOOLEAN")},
+		{`{[x]: x for x in [1]}`, errors.New("cannot use type ARRAY as key for hash")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+
+			if inspected, ok := tt.expected.(string); ok && evaluated.Type() != object.STRING_OBJECT {
+				if evaluated.Inspect() != inspected {
+					t.Errorf("evaluated.Inspect() wrong. want=%q, got=%q", inspected, evaluated.Inspect())
+				}
+				return
+			}
+
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestPipeAndComposition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[1, 2, 3] |> l`, 3},
+		{`let add = fn(a, b) { a + b }; 1 |> add(2)`, 3},
+		{`1..6 |> filter(fn(x) { x > 3 }) |> map(fn(x) { x * 10 }) |> array`, []interface{}{40, 50, 60}},
+		{`let sub = fn(a, b) { a - b }; 10 |> sub(3) |> sub(2)`, 5},
+		{`1 |> 5`, errors.New("not a function: INTEGER")},
+		{`1 |> missing(2)`, errors.New("identifier not found: missing")},
+		{`let inc = fn(x) { x + 1 }; let double = fn(x) { x * 2 }; (inc >> double)(3)`, 8},
+		{`let inc = fn(x) { x + 1 }; let double = fn(x) { x * 2 }; (inc << double)(3)`, 7},
+		{`let add = fn(a, b) { a + b }; let double = fn(x) { x * 2 }; (add >> double)(1, 2)`, 6},
+		{`let f = array >> l; f(1..4)`, 4},
+		{`let inc = fn(x) { x + 1 }; 3 |> inc >> inc >> inc`, 6},
+		{`let fail = fn(x) { x + true }; (l >> fail)([1])`, errors.New("type mismatch: INTEGER + BOOLEAN")},
+		{`(l >> l)([1])`, errors.New("argument to `l` not supported. got=INTEGER")},
+		{`let inc = fn(x) { x + 1 }; inc >> 1`, errors.New("type mismatch: FUNCTION >> INTEGER")},
+		{`1 >> 2`, errors.New("unknown operator: INTEGER >> INTEGER")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestLambdasAndPartialApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let double = x => x * 2; double(4)`, 8},
+		{`let add = (a, b) => a + b; add(1, 2)`, 3},
+		{`(() => 5)()`, 5},
+		{`let adder = x => y => x + y; adder(1)(2)`, 3},
+		{`array(map(1..3, x => x * x))`, []interface{}{1, 4, 9}},
+		{`[1, 2, 3] |> map(_, x => x + 1) |> array`, []interface{}{2, 3, 4}},
+		{`let add = fn(a, b) { a + b }; let inc = add(1, _); inc(5)`, 6},
+		{`let sub = fn(a, b) { a - b }; let fromTen = sub(10, _); fromTen(3)`, 7},
+		{`let sub = fn(a, b) { a - b }; let minus = sub(_, _); minus(10, 4)`, 6},
+		{`let f = push(_, 1); f([0])`, []interface{}{0, 1}},
+		{`x => x * 2`, "fn(x) {\n{ (x * 2) }\n}"},
+		{`let add = fn(a, b) { a + b }; add(1, _)`, "fn(_1) {\n{ add(1, _1) }\n}"},
+		{`let addThree = fn(a, b, c) { a + b + c }; let c = curry(addThree); c(1)(2)(3)`, 6},
+		{`let addThree = fn(a, b, c) { a + b + c }; let c = curry(addThree); let addOne = c(1); (addOne(2)(3), addOne(5)(5))`, Tuple{6, 11}},
+		{`let add = fn(a, b) { a + b }; curry(add)`, "fn(_1) {\n{ fn(_2) { _0(_1, _2) } }\n}"},
+		{`let add = fn(a, b) { a + b }; curry(add)(1)`, "fn(_2) {\n{ _0(_1, _2) }\n}"},
+		{`curry(push, 2)([])`, "fn(_2) {\n{ _0(_1, _2) }\n}"},
+		{`let f = fn() { 1 }; curry(f) == f`, true},
+		{`let c = curry(push, 2); let pushTo = c([]); pushTo(1)`, []interface{}{1}},
+		{`curry(push)`, errors.New("curry needs the number of arguments of a builtin function as second argument")},
+		{`curry(1)`, errors.New("first argument to curry has to be a function, got INTEGER instead")},
+		{`curry(push, -1)`, errors.New("number of arguments passed to curry has to be between 0 and 255, got -1")},
+		{`curry(push, 9223372036854775807)`, errors.New("number of arguments passed to curry has to be between 0 and 255, got 9223372036854775807")},
+		{`curry(fn(a, b) { a + b }, 256)`, errors.New("number of arguments passed to curry has to be between 0 and 255, got 256")},
+		{`curry(push, 0) == push`, true},
+		{`curry(fn(a, b) { a + true })(1)(2)`, errors.New("type mismatch: INTEGER + BOOLEAN")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+
+			if inspected, ok := tt.expected.(string); ok && evaluated.Type() != object.STRING_OBJECT {
+				if evaluated.Inspect() != inspected {
+					t.Errorf("evaluated.Inspect() wrong. want=%q, got=%q", inspected, evaluated.Inspect())
+				}
+				return
+			}
+
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let sum = fn(n, acc) { if n == 0 { acc } else { sum(n - 1, acc + n) } }; sum(100000, 0)`, Maybe{5000050000}},
+		{`let sum = fn(n, acc) { if n == 0 { return acc; }; return sum(n - 1, acc + n); }; sum(100000, 0)`, Maybe{5000050000}},
+		{`let count = fn(n) { if n == 0 { return 0; }; count(n - 1) }; count(100000)`, Maybe{0}},
+		{`
+		let isEven = fn(n) { if n == 0 { true } else { isOdd(n - 1) } };
+		let isOdd = fn(n) { if n == 0 { false } else { isEven(n - 1) } };
+		isEven(100001)
+		`, Maybe{false}},
+		{`let loop = fn(n) { if n > 0 { loop(n - 1) } }; loop(100000)`, Maybe{nil}},
+		{`let reduce = fn(xs, acc, f) { if l(xs) == 0 { acc } else { reduce(rest(xs), f(acc, first(xs).value), f) } }; reduce(vector(1..100000), 0, (a, b) => a + b)`, Maybe{5000050000}},
+		{`let f = fn(n) { if n == 0 { 1 + true } else { f(n - 1) } }; f(100000)`, errors.New("type mismatch: INTEGER + BOOLEAN")},
+		{`let f = fn() { g() }; let g = fn() { 5 }; f()`, 5},
+		{`let f = fn() { if true { g() } }; let g = fn() { 5 }; f()`, Maybe{5}},
+		{`let f = fn(n) { n }; return f(3);`, 3},
+		{`let f = fn(a, b) { a }; let g = fn() { f(1) }; g()`, errors.New("missing parameters \"b\" in function call")},
+	}
+
+	// Without tail calls the deep recursions exceed this stack size.
+	defer debug.SetMaxStack(debug.SetMaxStack(8 << 20))
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestRecursionDepth(t *testing.T) {
+	tests := []struct {
+		input        string
+		maxCallDepth int
+		expected     interface{}
+	}{
+		{`let f = fn(n) { if n == 0 { 0 } else { 1 + f(n - 1) } }; f(100000)`, 0, errors.New("maximum recursion depth exceeded, called from f at 1:44 <- f at 1:44 <- f at 1:44 <- f at 1:44 <- f at 1:44 <- 9996 more")},
+		{`let f = fn(n) { if n == 0 { 0 } else { 1 + f(n - 1) } }; f(10)`, 10, errors.New("maximum recursion depth exceeded, called from f at 1:44 <- f at 1:44 <- f at 1:44 <- f at 1:44 <- f at 1:44 <- 6 more")},
+		{`let f = fn(n) { if n > 0 { f(n - 1); }; n }; f(9)`, 10, 9},
+		{`let f = fn(n) { if n > 0 { f(n - 1); }; n }; f(10)`, 10, errors.New("maximum recursion depth exceeded, called from f at 1:28 <- f at 1:28 <- f at 1:28 <- f at 1:28 <- f at 1:28 <- 6 more")},
+		{`let g = fn() { g() + 1 }; let f = fn() { g() }; f()`, 3, errors.New("maximum recursion depth exceeded, called from g at 1:16 <- g at 1:16 <- g at 1:16 <- g at 1:42")},
+		{`let f = fn() { (fn() { f() })() + 1 }; f()`, 2, errors.New("maximum recursion depth exceeded, called from <anonymous> at 1:17 <- f at 1:24 <- f at 1:40")},
+		{`let f = fn(n) { if n == 0 { 0 } else { f(n - 1) } }; f(100)`, 10, Maybe{0}},
+		// Calls made by builtins continue the stack of their caller.
+		{`let f = fn(n) { if n == 0 { 0 } else { 1 + curry(f)(n - 1) } }; f(100000)`, 10, errors.New("maximum recursion depth exceeded, called from <anonymous> at 1:44 <- _0 at 1:44 <- _0 at 1:44 <- _0 at 1:44 <- _0 at 1:44 <- 6 more")},
+		{`let f = fn(n) { if n == 0 { 0 } else { 1 + (f >> fn(x) { x })(n - 1) } }; f(100000)`, 10, errors.New("maximum recursion depth exceeded, called from <anonymous> at 1:15 <- <anonymous> at 1:47 <- <anonymous> at 1:15 <- <anonymous> at 1:47 <- <anonymous> at 1:15 <- 6 more")},
+		{`let f = fn(n) { if n == 0 { 0 } else { 1 + first(array(map([n - 1], f))) } }; f(100000)`, 10, errors.New("maximum recursion depth exceeded, called from <anonymous> at 1:15 <- map at 1:56 <- <anonymous> at 1:15 <- map at 1:56 <- <anonymous> at 1:15 <- 6 more")},
+		{`let f = fn(n) { if n == 0 { true } else { l(array(filter([n - 1], f))) > 0 } }; f(100000)`, 10, errors.New("maximum recursion depth exceeded, called from <anonymous> at 1:15 <- filter at 1:51 <- <anonymous> at 1:15 <- filter at 1:51 <- <anonymous> at 1:15 <- 6 more")},
+		{`let f = fn(n) { let it = {"next": fn() { f(n - 1) }}; for x in it { }; 0 }; f(100000)`, 10, errors.New("maximum recursion depth exceeded, called from <anonymous> at 1:40 <- f at 1:42 <- f at 1:42 <- f at 1:42 <- f at 1:42 <- 6 more")},
+		{`let it = {"next": fn() { for x in it { }; some(1) }}; for x in it { }`, 10, errors.New("maximum recursion depth exceeded, called from <anonymous> at 1:24 <- <anonymous> at 1:24 <- <anonymous> at 1:24 <- <anonymous> at 1:24 <- <anonymous> at 1:24 <- 6 more")},
+		{`let it = map([1, 2], fn(x) { next(it) }); next(it)`, 10, errors.New("iterator already running")},
+		{`let it = filter([1, 2], fn(x) { next(it) }); next(it)`, 10, errors.New("iterator already running")},
+		{`let it = iter({"next": fn() { next(it) }}); next(it)`, 10, errors.New("iterator already running")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			env := object.NewEnvironment()
+			env.SetMaxCallDepth(tt.maxCallDepth)
+
+			testObjects(t, Eval(program, env), tt.expected)
+		})
+	}
+}
+
+func TestConditionModes(t *testing.T) {
+	tests := []struct {
+		input    string
+		mode     object.ConditionMode
+		expected interface{}
+	}{
+		{"if (1) { 10 }", 0, errors.New("condition must be BOOLEAN, got INTEGER")},
+		{"if (1) { 10 }", object.STRICT_CONDITIONS, errors.New("condition must be BOOLEAN, got INTEGER")},
+		{"if true { 1 } else { 2 }", object.STRICT_CONDITIONS, Maybe{1}},
+		{`if "" { 1 } else { 2 }`, object.STRICT_CONDITIONS, errors.New("condition must be BOOLEAN, got STRING")},
+		{"[x for x in [1, 2] if x]", object.STRICT_CONDITIONS, errors.New("condition must be BOOLEAN, got INTEGER")},
+		{"!1", object.STRICT_CONDITIONS, errors.New("condition must be BOOLEAN, got INTEGER")},
+		{"!5", 0, errors.New("condition must be BOOLEAN, got INTEGER")},
+		{"!if true { true }", object.STRICT_CONDITIONS, errors.New("condition must be BOOLEAN, got MAYBE")},
+		{"if (1) { 10 }", object.TRUTHY_CONDITIONS, Maybe{10}},
+		{"if (0) { 10 } else { 20 }", object.TRUTHY_CONDITIONS, Maybe{20}},
+		{`if "a" { 1 } else { 2 }`, object.TRUTHY_CONDITIONS, Maybe{1}},
+		{`if "" { 1 } else { 2 }`, object.TRUTHY_CONDITIONS, Maybe{2}},
+		{"if [] { 1 } else { 2 }", object.TRUTHY_CONDITIONS, Maybe{2}},
+		{"if [0] { 1 } else { 2 }", object.TRUTHY_CONDITIONS, Maybe{1}},
+		{"if {} { 1 } else { 2 }", object.TRUTHY_CONDITIONS, Maybe{2}},
+		{`if {"a": 1} { 1 } else { 2 }`, object.TRUTHY_CONDITIONS, Maybe{1}},
+		{"if 1..<1 { 1 } else { 2 }", object.TRUTHY_CONDITIONS, Maybe{2}},
+		{"if if false { 1 } { 1 } else { 2 }", object.TRUTHY_CONDITIONS, Maybe{2}},
+		{"if if true { false } { 1 } else { 2 }", object.TRUTHY_CONDITIONS, Maybe{1}},
+		{"if puts() { 1 } else { 2 }", object.TRUTHY_CONDITIONS, Maybe{2}},
+		{"if fn() {} { 1 } else { 2 }", object.TRUTHY_CONDITIONS, Maybe{1}},
+		{"!0", object.TRUTHY_CONDITIONS, true},
+		{`!"a"`, object.TRUTHY_CONDITIONS, false},
+		{"[x for x in [0, 1, 2] if x]", object.TRUTHY_CONDITIONS, []interface{}{1, 2}},
+		{"let f = fn(x) { if x { 1 } else { 2 } }; f(0)", object.TRUTHY_CONDITIONS, Maybe{2}},
+		{"array(filter([1, 2, 3], fn(x) { x }))", object.STRICT_CONDITIONS, errors.New("predicate has to return a BOOLEAN, got INTEGER instead")},
+		{"array(filter([1, 2, 3], fn(x) { if x > 1 { x } }))", object.STRICT_CONDITIONS, errors.New("predicate has to return a BOOLEAN, got MAYBE instead")},
+		{"array(filter([1, 2, 3], fn(x) { if x > 1 { true } else { false } }))", object.STRICT_CONDITIONS, errors.New("predicate has to return a BOOLEAN, got MAYBE instead")},
+		{"array(filter([0, 1, 2], fn(x) { x }))", object.TRUTHY_CONDITIONS, []interface{}{1, 2}},
+		{"array(filter([0, 1, 2], fn(x) { if x > 0 { x } }))", object.TRUTHY_CONDITIONS, []interface{}{1, 2}},
+		{`array(filter(["", "a"], fn(x) { x }))`, object.TRUTHY_CONDITIONS, []interface{}{"a"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			env := object.NewEnvironment()
+			env.SetConditionMode(tt.mode)
+
+			testObjects(t, Eval(program, env), tt.expected)
+		})
+	}
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let log = []; let f = fn() { defer push(log, "a"); push(log, "b"); }; f(); log`, []interface{}{"b", "a"}},
+		{`let log = []; let f = fn() { defer push(log, 1); defer push(log, 2); defer push(log, 3); }; f(); log`, []interface{}{3, 2, 1}},
+		{`let log = []; let f = fn() { defer push(log, "a"); return 1; push(log, "b"); }; (f(), log)`, Tuple{1, []interface{}{"a"}}},
+		{`let log = []; let f = fn() { defer push(log, "a"); x; }; (f(), log)`, errors.New("identifier not found: x")},
+		{`let f = fn() { defer x; 1 }; f()`, errors.New("identifier not found: x")},
+		{`let f = fn() { defer x; y }; f()`, errors.New("identifier not found: y")},
+		{`let log = []; let f = fn() { let x = 1; defer push(log, x); let x = 2; }; f(); log`, []interface{}{2}},
+		{`let log = []; let f = fn() { for i in [1, 2] { defer push(log, i) }; push(log, 0) }; f(); log`, []interface{}{0, 2, 1}},
+		{`let log = []; let f = fn() { if true { defer push(log, "a") }; push(log, "b") }; f(); log`, []interface{}{"b", "a"}},
+		{`let log = []; let g = fn() { defer push(log, "g"); }; let f = fn() { defer push(log, "f"); g() }; f(); log`, []interface{}{"g", "f"}},
+		{`let log = []; let g = fn() { push(log, "g"); 1 }; let f = fn() { defer push(log, "f"); return g(); }; (f(), log)`, Tuple{1, []interface{}{"g", "f"}}},
+		{`let log = []; let f = fn(n) { defer push(log, n); if n > 0 { f(n - 1) } else { 0 } }; f(2); log`, []interface{}{0, 1, 2}},
+		{`let log = []; let g = fn*() { defer push(log, "done"); yield 1; yield 2 }; (array(g()), log)`, Tuple{[]interface{}{1, 2}, []interface{}{"done"}}},
+		{`let log = []; let f = fn() { defer push(log, "a"); 1 }; (array(map([1, 2], fn(x) { f() })), log)`, Tuple{[]interface{}{1, 1}, []interface{}{"a", "a"}}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestDeferOnError(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+		log      []interface{}
+	}{
+		{`let log = []; let f = fn() { defer push(log, "a"); x; }; f()`, errors.New("identifier not found: x"), []interface{}{"a"}},
+		{`let log = []; let f = fn() { defer push(log, "a"); if true { 1 + true } }; f()`, errors.New("type mismatch: INTEGER + BOOLEAN"), []interface{}{"a"}},
+		{`let log = []; let g = fn() { x }; let f = fn() { defer push(log, "f"); g() }; f()`, errors.New("identifier not found: x"), []interface{}{"f"}},
+		{`let log = []; let f = fn() { defer push(log, 1); defer x; defer push(log, 2); }; f()`, errors.New("identifier not found: x"), []interface{}{2, 1}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			env := object.NewEnvironment()
+
+			testObjects(t, Eval(program, env), tt.expected)
+
+			log, _ := env.Get("log")
+			testObjects(t, log, tt.log)
+		})
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	tests := []struct {
+		input     string
+		stack     []object.StackFrame
+		traceback string
+	}{
+		{"1 + true", nil, ""},
+		{
+			"let inner = fn(x) { x + true };\nlet outer = fn(x) { inner(x) + 1 };\nouter(1)",
+			[]object.StackFrame{{Function: "inner", Line: 2, Column: 21}, {Function: "outer", Line: 3, Column: 1}},
+			"\tin inner called at 2:21\n\tin outer called at 3:1\n",
+		},
+		{
+			"let inner = fn(x) { x + true };\nlet outer = fn(x) { inner(x) };\nouter(1)",
+			[]object.StackFrame{{Function: "inner", Line: 2, Column: 21}},
+			"\tin inner called at 2:21\n",
+		},
+		{
+			"let f = fn(n) { if n > 0 { f(n - 1); }; n + true }; f(3)",
+			[]object.StackFrame{{Function: "f", Line: 1, Column: 28}, {Function: "f", Line: 1, Column: 28}, {Function: "f", Line: 1, Column: 28}, {Function: "f", Line: 1, Column: 53}},
+			"\tin f called at 1:28 (3 times)\n\tin f called at 1:53\n",
+		},
+		{
+			"let f = fn() { (fn() { 1 + true })() }; f()",
+			[]object.StackFrame{{Function: "<anonymous>", Line: 1, Column: 17}},
+			"\tin <anonymous> called at 1:17\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			err, ok := testEval(tt.input).(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T", err)
+			}
+
+			if !reflect.DeepEqual(err.Stack, tt.stack) {
+				t.Errorf("wrong stack. want=%+v, got=%+v", tt.stack, err.Stack)
+			}
+
+			if err.Traceback() != tt.traceback {
+				t.Errorf("wrong traceback. want=%q, got=%q", tt.traceback, err.Traceback())
+			}
+		})
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"let = 5; 1", errors.New("cannot evaluate code with syntax errors")},
+		{"let x = ) + 5; x", errors.New("cannot evaluate code with syntax errors")},
+		{"let f = fn() { let = 1; 2 }; f()", errors.New("cannot evaluate code with syntax errors")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestValuelessOperands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"puts(1) + 1", errors.New("type mismatch: UNIT + INTEGER")},
+		{"-puts(1)", errors.New("unknown operator: -UNIT")},
+		{"!puts(1)", errors.New("condition must be BOOLEAN, got UNIT")},
+		{"{puts(1): 1}", errors.New("cannot use type UNIT as key for hash")},
+		{"l(puts(1))", errors.New("argument to `l` not supported. got=UNIT")},
+		{"let f = fn() {}; f() + 1", errors.New("type mismatch: UNIT + INTEGER")},
+		{"[1][puts(1)]", errors.New("cannot use UNIT as index for array")},
+		{"puts(1).value", errors.New(`UNIT has no property "value".`)},
+		{"puts(1)(1)", errors.New("not a function: UNIT")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestUnit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"", UNIT},
+		{"let x = 1;", UNIT},
+		{"let (a, b) = (1, 2);", UNIT},
+		{"for x in [1] { x };", UNIT},
+		{"puts(1)", UNIT},
+		{"let x = puts(1); x", UNIT},
+		{"let f = fn() {}; f()", UNIT},
+		{"let f = fn() { let x = 1; }; f()", UNIT},
+		{"puts(1) == puts(2)", true},
+		{"puts(1) == 1", false},
+		{"[puts(1)]", []interface{}{UNIT}},
+		{"array(map([1], fn(x) {}))", []interface{}{UNIT}},
+		{"let g = fn*() { yield puts(1) }; array(g())", []interface{}{UNIT}},
+		{"if true { puts(1) }", Maybe{nil}},
+		{"if true { let x = 1; }", Maybe{nil}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestRuntimeChecks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 / 0", errors.New("division by zero")},
+		{"first([])", Maybe{nil}},
+		{"last([])", Maybe{nil}},
+		{`"a" * -1`, errors.New("cannot repeat string a negative number of times: -1")},
+		{`"ab" * 9223372036854775807`, errors.New("repeated string is too long, the limit is 268435456 bytes")},
+		{`"ab" * 134217729`, errors.New("repeated string is too long, the limit is 268435456 bytes")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestEvalSafely(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("boom", &object.Builtin{Fn: func(args ...object.Object) (object.Object, error) {
+		panic("boom")
+	}})
+
+	tests := []string{
+		"boom()",
+		"let g = fn*() { yield boom() }; array(g())",
+	}
+
+	for i, input := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			program := parser.New(lexer.New(input)).ParseProgram()
+			testObjects(t, EvalSafely(program, env), errors.New("internal error: boom"))
+		})
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{`lenght([1])`, errors.New(`identifier not found: lenght (did you mean "l"?)`)},
+		{`let total = 1; totl`, errors.New(`identifier not found: totl (did you mean "total"?)`)},
+		{`let f = fn(value) { valeu }; f(1)`, errors.New(`identifier not found: valeu (did you mean "value"?)`)},
+		{`let first_name = 1; let last_name = 2; fist_name`, errors.New(`identifier not found: fist_name (did you mean "first_name" or "last_name"?)`)},
+		{`filtr`, errors.New(`identifier not found: filtr (did you mean "filter"?)`)},
+		{`len([1])`, errors.New(`identifier not found: len (did you mean "l"?)`)},
+		{`xyz`, errors.New(`identifier not found: xyz`)},
+		{`if (true) { 1 }.valeu`, errors.New(`MAYBE has no property "valeu" (did you mean "value"?).`)},
+		{`if (true) { 1 }.hasvalue`, errors.New(`MAYBE has no property "hasvalue" (did you mean "hasValue"?).`)},
+		{`next(iter([1])).vlaue`, errors.New(`MAYBE has no property "vlaue" (did you mean "value"?).`)},
+		{`let g = fn*() { yield 1 }; next(g()).hasValeu`, errors.New(`MAYBE has no property "hasValeu" (did you mean "hasValue"?).`)},
+		{`[1].value`, errors.New(`ARRAY has no property "value".`)},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			testObjects(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
     {
@@ -531,6 +1465,50 @@ func TestHashLiterals(t *testing.T) {
 
 }
 
+func TestHashLiteralOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"c": 1, "a": 2, "b": 3}`, `{"c": 1, "a": 2, "b": 3}`},
+		{`{3: "c", 1: "a", 2: "b"}`, `{3: "c", 1: "a", 2: "b"}`},
+		{`{"a": 1, "b": 2, "a": 3}`, `{"a": 3, "b": 2}`},
+		{`let log = []; {"b": push(log, "b"), "a": push(log, "a"), "c": push(log, "c")}; log`, `["b", "a", "c"]`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			for run := 0; run < 10; run++ {
+				evaluated := testEval(tt.input)
+				if evaluated.Inspect() != tt.expected {
+					t.Fatalf("evaluated.Inspect() wrong. want=%q, got=%q", tt.expected, evaluated.Inspect())
+				}
+			}
+		})
+	}
+}
+
+func TestInspectSelfReferentialValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = []; push(a, a); a`, `[[...]]`},
+		{`let a = [1]; push(a, (a, 2)); a`, `[1, ([...], 2)]`},
+		{`let a = [1]; push(a, a); first(a)`, `maybe(1)`},
+		{`let a = [1]; push(a, a); last(a)`, `maybe([1, [...]])`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("evaluated.Inspect() wrong. want=%q, got=%q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -574,6 +1552,86 @@ func TestPropertyExpression(t *testing.T) {
 
 type Maybe struct{ Value interface{} }
 
+func TestSets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{1, 2, 2, 1}`, "{1, 2}"},
+		{`{"b", "a", 3}`, `{3, "a", "b"}`},
+		{`set()`, "set()"},
+		{`set([3, 1, 3])`, "{1, 3}"},
+		{`set((1, "a"))`, `{1, "a"}`},
+		{`set([[1]])`, errors.New("cannot use type ARRAY as element of set")},
+		{`{[1]}`, errors.New("cannot use type ARRAY as element of set")},
+		{`{1, 2} | {2, 3}`, "{1, 2, 3}"},
+		{`{1, 2} & {2, 3}`, "{2}"},
+		{`{1, 2} - {2, 3}`, "{1}"},
+		{`{1, 2} * {2, 3}`, errors.New("unknown operator: SET * SET")},
+		{`l({1, 2, 3})`, 3},
+		{`array({3, 2, 1})`, []interface{}{1, 2, 3}},
+		{`1 in {1, 2}`, true},
+		{`3 in {1, 2}`, false},
+		{`[1] in {1, 2}`, false},
+		{`(1, 2) in {(1, 2)}`, true},
+		{`"a" in {"a": 1}`, true},
+		{`"b" in {"a": 1}`, false},
+		{`2 in [1, 2]`, true},
+		{`"x" in (1, "x")`, true},
+		{`"ell" in "hello"`, true},
+		{`1 in "hello"`, errors.New("type mismatch: INTEGER in STRING")},
+		{`1 in 2`, errors.New("unknown operator: INTEGER in INTEGER")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+
+			if inspected, ok := tt.expected.(string); ok {
+				if evaluated.Inspect() != inspected {
+					t.Errorf("evaluated.Inspect() wrong. want=%q, got=%q", inspected, evaluated.Inspect())
+				}
+				return
+			}
+
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
+type Tuple []interface{}
+
+func TestTuples(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`()`, Tuple{}},
+		{`(1,)`, Tuple{1}},
+		{`(1, "two", 1 + 2)`, Tuple{1, "two", 3}},
+		{`(1, (2, 3))`, Tuple{1, Tuple{2, 3}}},
+		{`(1, 2)[1]`, 2},
+		{`(1, 2)[2]`, errors.New("index 2 out of range for tuple of length 2")},
+		{`(1, 2)["a"]`, errors.New("cannot use STRING as index for tuple")},
+		{`l((1, 2, 3))`, 3},
+		{`divmod(7, 2)`, Tuple{3, 1}},
+		{`divmod(7, 0)`, errors.New("division by zero")},
+		{`let (q, r) = divmod(7, 2); q * 10 + r`, 31},
+		{`let t = (1, "a"); let (x, y) = t; y`, "a"},
+		{`let (x, y) = (1, 2, 3);`, errors.New("cannot destructure tuple of length 3 into 2 names")},
+		{`let (x, y) = [1, 2];`, errors.New("cannot destructure ARRAY, expected TUPLE")},
+		{`{(1, "a"): "one"}[(1, "a")]`, Maybe{"one"}},
+		{`{(1, [2]): "one"}`, errors.New("cannot use type TUPLE as key for hash")},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d - %s", i, tt.input), func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			testObjects(t, evaluated, tt.expected)
+		})
+	}
+}
+
 func TestMaybe(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -688,6 +1746,11 @@ func testObjects(t *testing.T, obj object.Object, expected interface{}) bool {
 			t.Errorf("errObj.message is not %q. got=%q", e.Error(), errObj.Message)
 			return false
 		}
+	case *object.Unit:
+		if obj != UNIT {
+			t.Errorf("object is not unit. got=%T (%+v)", obj, obj)
+			return false
+		}
 	case nil:
 		if obj != nil {
 			t.Errorf("object is not nil. got=%T (%v)", expected, e)
@@ -706,6 +1769,23 @@ func testObjects(t *testing.T, obj object.Object, expected interface{}) bool {
 		if !testObjects(t, maybe.Value, e.Value) {
 			return false
 		}
+	case Tuple:
+		tuple, ok := obj.(*object.Tuple)
+		if !ok {
+			t.Errorf("obj is not of type object.Tuple. got=%T (%+v)", obj, obj)
+			return false
+		}
+
+		if len(tuple.Elements) != len(e) {
+			t.Errorf("Length of tuple elements does not fit expected length of elements. want=%d, got=%d", len(e), len(tuple.Elements))
+			return false
+		}
+
+		for i := range e {
+			if !testObjects(t, tuple.Elements[i], e[i]) {
+				return false
+			}
+		}
 	case []interface{}:
 		arrObj, ok := obj.(*object.Array)
 		if !ok {
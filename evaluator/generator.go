@@ -0,0 +1,166 @@
+package evaluator
+
+import (
+	"runtime"
+
+	"github.com/hendrikbursian/monkey-programming-language/ast"
+	"github.com/hendrikbursian/monkey-programming-language/object"
+)
+
+// generator is the iterator returned by calling a `fn*` function. The body
+// runs in its own goroutine in lockstep with the consumer: Next resumes the
+// body and waits until it yields the next value or finishes, so the body and
+// the consumer never run at the same time.
+//
+// Code deferred by the body runs when the body finishes. It does not run if
+// the generator is abandoned before that: the goroutine is only stopped once
+// the generator has been garbage collected, and deferred code running then
+// would run concurrently with the consumer.
+type generator struct {
+	state *generatorState
+}
+
+// generatorState is shared with the goroutine. It is kept apart from the
+// generator, so that an abandoned generator can be garbage collected and its
+// finalizer can stop the goroutine.
+type generatorState struct {
+	function *object.Function
+	env      *object.Environment
+
+	started  bool
+	finished bool
+
+	// running is set while the body runs. A body that advances its own
+	// generator would wait for itself forever.
+	running bool
+
+	// abandoned is set by the goroutine once it has been told to stop.
+	abandoned bool
+
+	resume chan struct{}
+	values chan generatorMessage
+	done   chan struct{}
+}
+
+type generatorMessage struct {
+	value    object.Object
+	finished bool
+}
+
+func newGenerator(function *object.Function, env *object.Environment) object.Object {
+	g := &generator{state: &generatorState{
+		function: function,
+		env:      env,
+		resume:   make(chan struct{}),
+		values:   make(chan generatorMessage),
+		done:     make(chan struct{}),
+	}}
+
+	runtime.SetFinalizer(g, func(g *generator) { close(g.state.done) })
+
+	return &object.Sequence{Iterator: g}
+}
+
+func (g *generator) Next() (object.Object, bool) {
+	state := g.state
+	if state.finished {
+		return nil, false
+	}
+
+	if state.running {
+		// The error has no position, the caller positions it.
+		return newError(0, 0, "generator already running"), true
+	}
+
+	state.running = true
+	defer func() { state.running = false }()
+
+	if state.started {
+		state.resume <- struct{}{}
+	} else {
+		state.started = true
+		go state.run()
+	}
+
+	message := <-state.values
+
+	// The finalizer must not stop the goroutine while it is running for
+	// this call.
+	runtime.KeepAlive(g)
+
+	if message.finished {
+		state.finished = true
+		return message.value, message.value != nil
+	}
+
+	return message.value, true
+}
+
+func (state *generatorState) run() {
+	state.env.SetYield(state.yield)
+
+	message := generatorMessage{finished: true}
+	defer func() {
+		// A panic in the goroutine would take down the host, so it is
+		// handed to the consumer as an error instead.
+		if r := recover(); r != nil {
+			message.value = internalError(state.function.Body, r)
+			select {
+			case state.values <- message:
+			case <-state.done:
+			}
+		}
+	}()
+
+	result := resolveTailCall(unwrapReturnValue(Eval(state.function.Body, state.env)))
+	if state.abandoned {
+		return
+	}
+
+	if result = runDeferred(state.env.Deferred(), result); isError(result) {
+		message.value = result
+	}
+
+	select {
+	case state.values <- message:
+	case <-state.done:
+	}
+}
+
+// yield hands value to the consumer and blocks until the next value is
+// requested. It returns false if the generator has been abandoned.
+func (state *generatorState) yield(value object.Object) bool {
+	select {
+	case state.values <- generatorMessage{value: value}:
+	case <-state.done:
+		state.abandoned = true
+		return false
+	}
+
+	select {
+	case <-state.resume:
+		return true
+	case <-state.done:
+		state.abandoned = true
+		return false
+	}
+}
+
+func evalYieldStatement(node *ast.YieldStatement, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	yield, ok := env.Yield()
+	if !ok {
+		return newError(node.Line(), node.Column(), "yield outside of generator function")
+	}
+
+	if !yield(value) {
+		// The generator has been abandoned: unwind the body like a return.
+		return &object.ReturnValue{Value: &EMPTY_MAYBE}
+	}
+
+	return UNIT
+}
@@ -0,0 +1,328 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/hendrikbursian/monkey-programming-language/ast"
+	"github.com/hendrikbursian/monkey-programming-language/object"
+)
+
+var NEXT_KEY = (&object.String{Value: "next"}).HashKey()
+
+func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	result := evalLoop(node.Identifier, node.Names, node.Iterable, env, func(loopEnv *object.Environment) object.Object {
+		result := Eval(node.Body, loopEnv)
+		rt := result.Type()
+		if rt == object.RETURN_VALUE_OBJECT || rt == object.ERROR_OBJECT {
+			return result
+		}
+
+		return nil
+	})
+	if result != nil {
+		return result
+	}
+
+	return UNIT
+}
+
+func evalArrayComprehension(node *ast.ArrayComprehension, env *object.Environment) object.Object {
+	elements := []object.Object{}
+
+	err := evalComprehensionClause(node.Clause, env, func(loopEnv *object.Environment) object.Object {
+		element := Eval(node.Element, loopEnv)
+		if isError(element) {
+			return element
+		}
+
+		elements = append(elements, element)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func evalHashComprehension(node *ast.HashComprehension, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	err := evalComprehensionClause(node.Clause, env, func(loopEnv *object.Environment) object.Object {
+		key := Eval(node.Key, loopEnv)
+		if isError(key) {
+			return key
+		}
+
+		hashableKey, ok := toHashable(key)
+		if !ok {
+			return newError(node.Key.Line(), node.Key.Column(), "cannot use type %s as key for hash", key.Type())
+		}
+
+		value := Eval(node.Value, loopEnv)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(hashableKey.HashKey(), object.HashPair{Key: key, Value: value})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return hash
+}
+
+// evalComprehensionClause calls each for every value of the clause that
+// passes its condition, which is evaluated like the condition of an if
+// expression.
+func evalComprehensionClause(clause *ast.ComprehensionClause, env *object.Environment, each func(*object.Environment) object.Object) object.Object {
+	return evalLoop(clause.Identifier, clause.Names, clause.Iterable, env, func(loopEnv *object.Environment) object.Object {
+		if clause.Condition != nil {
+			condition := evalCondition(clause.Condition, loopEnv)
+			if isError(condition) {
+				return condition
+			}
+
+			if condition != TRUE {
+				return nil
+			}
+		}
+
+		return each(loopEnv)
+	})
+}
+
+// evalLoop calls body once for every value of iterable. Every iteration gets
+// a fresh environment enclosed by env, in which the value is bound to
+// identifier or destructured into names. The loop stops at the first non-nil
+// result of body, which is returned.
+func evalLoop(identifier *ast.Identifier, names []*ast.Identifier, iterable ast.Expression, env *object.Environment, body func(*object.Environment) object.Object) object.Object {
+	iterableObj := Eval(iterable, env)
+	if isError(iterableObj) {
+		return iterableObj
+	}
+
+	iterator, ok := iterate(env.Frame(), iterableObj)
+	if !ok {
+		return newError(iterable.Line(), iterable.Column(), "cannot iterate over %s", iterableObj.Type())
+	}
+
+	for {
+		value, ok := iterator.Next()
+		if !ok {
+			return nil
+		}
+
+		if err, ok := value.(*object.Error); ok {
+			if err.Line == 0 {
+				// Errors of the iterator itself are positioned at the loop.
+				return newError(iterable.Line(), iterable.Column(), "%s", err.Message)
+			}
+			return err
+		}
+
+		loopEnv := object.NewEnclosedEnvironment(env)
+		if identifier != nil {
+			loopEnv.Set(identifier.Value, value)
+		} else if err := destructure(iterable, names, value, loopEnv); err != nil {
+			return err
+		}
+
+		if result := body(loopEnv); result != nil {
+			return result
+		}
+	}
+}
+
+// destructure binds the elements of the tuple value to names.
+func destructure(iterable ast.Expression, names []*ast.Identifier, value object.Object, env *object.Environment) object.Object {
+	tuple, ok := value.(*object.Tuple)
+	if !ok {
+		return newError(iterable.Line(), iterable.Column(), "cannot destructure %s, expected TUPLE", value.Type())
+	}
+
+	if len(tuple.Elements) != len(names) {
+		return newError(iterable.Line(), iterable.Column(), "cannot destructure tuple of length %d into %d names", len(tuple.Elements), len(names))
+	}
+
+	for i, name := range names {
+		env.Set(name.Value, tuple.Elements[i])
+	}
+
+	return nil
+}
+
+// iterate returns an iterator over obj. Hashes with a callable "next" entry
+// are user-defined iterators: next is called until it returns an empty maybe.
+// All other hashes and the builtin collections implement object.Iterable.
+// Callbacks of the iterator are called on top of frame.
+func iterate(frame *object.Frame, obj object.Object) (object.Iterator, bool) {
+	if hash, ok := obj.(*object.Hash); ok {
+		if pair, ok := hash.Pairs[NEXT_KEY]; ok && isCallable(pair.Value) {
+			return userIterator(frame, pair.Value), true
+		}
+	}
+
+	if iterable, ok := obj.(object.Iterable); ok {
+		return iterable.Iterate(), true
+	}
+
+	return nil, false
+}
+
+func userIterator(frame *object.Frame, next object.Object) object.Iterator {
+	return exclusive(func() (object.Object, bool) {
+		result := applyCallback(frame, next)
+		if isError(result) {
+			return result, true
+		}
+
+		maybe, ok := result.(*object.Maybe)
+		if !ok {
+			line, column := 0, 0
+			if fn, ok := next.(*object.Function); ok {
+				line, column = fn.Body.Line(), fn.Body.Column()
+			}
+			return newError(line, column, "next has to return a MAYBE, got %s instead", result.Type()), true
+		}
+
+		if maybe.Value == nil {
+			return nil, false
+		}
+
+		return maybe.Value, true
+	})
+}
+
+// collect consumes obj and returns all of its values. Errors raised while
+// iterating are returned as *object.Error.
+func collect(frame *object.Frame, name string, obj object.Object) ([]object.Object, error) {
+	iterator, ok := iterate(frame, obj)
+	if !ok {
+		return nil, fmt.Errorf("argument to %s has to be iterable, got %s instead", name, obj.Type())
+	}
+
+	elements := []object.Object{}
+	for {
+		value, ok := iterator.Next()
+		if !ok {
+			return elements, nil
+		}
+
+		if err, ok := value.(*object.Error); ok {
+			return nil, err
+		}
+
+		elements = append(elements, value)
+	}
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSelected reports whether result, returned by predicate, keeps a value.
+// The result is treated like the condition of an if expression in the
+// environment of predicate.
+func isSelected(predicate, result object.Object) (bool, *object.Error) {
+	mode := object.STRICT_CONDITIONS
+	line, column := 0, 0
+	if fn, ok := predicate.(*object.Function); ok {
+		mode = fn.Env.ConditionMode()
+		line, column = fn.Body.Line(), fn.Body.Column()
+	}
+
+	selected, ok := conditionHolds(result, mode)
+	if !ok {
+		return false, newError(line, column, "predicate has to return a BOOLEAN, got %s instead", result.Type())
+	}
+
+	return selected, nil
+}
+
+// exclusive turns next into an iterator that reports an error instead of
+// advancing while it is already running. Callbacks are called on top of the
+// frame the iterator was created in, so an iterator that advances itself
+// would otherwise recurse without ever reaching the maximum call depth.
+func exclusive(next func() (object.Object, bool)) object.Iterator {
+	running := false
+	return object.IteratorFunc(func() (object.Object, bool) {
+		if running {
+			return newError(0, 0, "iterator already running"), true
+		}
+
+		running = true
+		defer func() { running = false }()
+		return next()
+	})
+}
+
+func lazyMap(frame *object.Frame, iterator object.Iterator, function object.Object) object.Iterator {
+	return exclusive(func() (object.Object, bool) {
+		value, ok := iterator.Next()
+		if !ok || isError(value) {
+			return value, ok
+		}
+
+		return applyCallback(frame, function, value), true
+	})
+}
+
+func lazyFilter(frame *object.Frame, iterator object.Iterator, predicate object.Object) object.Iterator {
+	return exclusive(func() (object.Object, bool) {
+		for {
+			value, ok := iterator.Next()
+			if !ok || isError(value) {
+				return value, ok
+			}
+
+			result := applyCallback(frame, predicate, value)
+			if isError(result) {
+				return result, true
+			}
+
+			selected, err := isSelected(predicate, result)
+			if err != nil {
+				return err, true
+			}
+
+			if selected {
+				return value, true
+			}
+		}
+	})
+}
+
+func lazyTake(iterator object.Iterator, n int64) object.Iterator {
+	return object.IteratorFunc(func() (object.Object, bool) {
+		if n <= 0 {
+			return nil, false
+		}
+
+		n--
+		return iterator.Next()
+	})
+}
+
+func lazyZip(iterators []object.Iterator) object.Iterator {
+	return object.IteratorFunc(func() (object.Object, bool) {
+		elements := make([]object.Object, 0, len(iterators))
+		for _, iterator := range iterators {
+			value, ok := iterator.Next()
+			if !ok || isError(value) {
+				return value, ok
+			}
+
+			elements = append(elements, value)
+		}
+
+		return &object.Tuple{Elements: elements}, true
+	})
+}
@@ -0,0 +1,150 @@
+package evaluator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hendrikbursian/monkey-programming-language/object"
+)
+
+// maxSuggestions is the number of names a did-you-mean hint lists at most.
+const maxSuggestions = 3
+
+// builtinAliases maps names that users coming from other languages try to
+// the builtin they are looking for.
+var builtinAliases = map[string]string{
+	"len":    "l",
+	"length": "l",
+	"size":   "l",
+	"count":  "l",
+	"print":  "puts",
+}
+
+// identifierSuggestions returns the names of env, its outer environments and
+// the builtins closest to name.
+func identifierSuggestions(name string, env *object.Environment) []string {
+	candidates := map[string]string{}
+	for _, candidate := range env.Names() {
+		candidates[candidate] = candidate
+	}
+
+	for candidate := range builtins {
+		candidates[candidate] = candidate
+	}
+
+	for alias, builtin := range builtinAliases {
+		if _, ok := candidates[alias]; !ok {
+			candidates[alias] = builtin
+		}
+	}
+
+	return suggest(name, candidates)
+}
+
+// propertySuggestions returns the properties of subject closest to name.
+func propertySuggestions(name string, subject object.Object) []string {
+	candidates := map[string]string{}
+	for property := range properties[subject.Type()] {
+		candidates[property] = property
+	}
+
+	return suggest(name, candidates)
+}
+
+// suggest returns the suggestions for the candidates closest to name by edit
+// distance. candidates maps the names compared with name to the suggestion
+// they stand for. Short names get no suggestions, there are too many close
+// ones.
+func suggest(name string, candidates map[string]string) []string {
+	limit := len(name) / 3
+
+	type match struct {
+		suggestion string
+		distance   int
+	}
+
+	best := map[string]int{}
+	for candidate, suggestion := range candidates {
+		distance := editDistance(name, candidate)
+		if suggestion == name || distance > limit {
+			continue
+		}
+
+		if d, ok := best[suggestion]; !ok || distance < d {
+			best[suggestion] = distance
+		}
+	}
+
+	matches := []match{}
+	for suggestion, distance := range best {
+		matches = append(matches, match{suggestion, distance})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].suggestion < matches[j].suggestion
+	})
+
+	suggestions := []string{}
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, matches[i].suggestion)
+	}
+
+	return suggestions
+}
+
+// didYouMean formats suggestions as ` (did you mean "a" or "b"?)`, or returns
+// an empty string if there are none.
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+
+	quoted := []string{}
+	for _, suggestion := range suggestions {
+		quoted = append(quoted, fmt.Sprintf("%q", suggestion))
+	}
+
+	alternatives := quoted[len(quoted)-1]
+	if len(quoted) > 1 {
+		alternatives = strings.Join(quoted[:len(quoted)-1], ", ") + " or " + alternatives
+	}
+
+	return fmt.Sprintf(" (did you mean %s?)", alternatives)
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters needed to turn a into b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	beforePrevious := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], beforePrevious[j-2]+1)
+			}
+		}
+
+		beforePrevious, previous, current = previous, current, beforePrevious
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,100 @@
+package evaluator
+
+import (
+	"github.com/hendrikbursian/monkey-programming-language/ast"
+	"github.com/hendrikbursian/monkey-programming-language/object"
+)
+
+const TAIL_CALL_OBJECT = "TAIL_CALL"
+
+// tailCall is a call in tail position whose function and arguments have been
+// evaluated, but which has not been made yet. It never leaves the evaluator.
+type tailCall struct {
+	function object.Object
+	args     []object.Object
+
+	// caller is the frame of the function that makes the call, name and
+	// position describe the call itself.
+	caller *object.Frame
+	name   string
+	line   int
+	column int
+
+	// wrap is set if the call is the value of an if expression, whose result
+	// has to be wrapped in a maybe once the call has been made.
+	wrap bool
+}
+
+func (call *tailCall) Type() object.ObjectType { return TAIL_CALL_OBJECT }
+func (call *tailCall) Inspect() string         { return "tail call" }
+
+// evalTailCall evaluates the function and the arguments of node and returns
+// the call as *tailCall without making it.
+func evalTailCall(node *ast.CallExpression, env *object.Environment) object.Object {
+	function := Eval(node.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return &tailCall{
+		function: function,
+		args:     args,
+		caller:   env.Frame(),
+		name:     calleeName(node.Function),
+		line:     node.Line(),
+		column:   node.Column(),
+	}
+}
+
+// resolveTailCall makes obj if it is a tail call and returns its result.
+func resolveTailCall(obj object.Object) object.Object {
+	call, ok := obj.(*tailCall)
+	if !ok {
+		return obj
+	}
+
+	result := applyFunction(call.caller.Push(call.name, call.line, call.column), call.function, call.args)
+	if call.wrap && !isError(result) {
+		return wrapMaybe(result)
+	}
+
+	return result
+}
+
+// evalTailBlock evaluates a block in tail position, like the body of a
+// function. A call as its last expression, also in the branches of an if
+// expression, is returned as *tailCall.
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object = UNIT
+
+	for i, statement := range block.Statements {
+		if expression, ok := statement.(*ast.ExpressionStatement); ok && i == len(block.Statements)-1 {
+			return evalTail(expression.Expression, env)
+		}
+
+		result = Eval(statement, env)
+
+		rt := result.Type()
+		if rt == object.RETURN_VALUE_OBJECT || rt == object.ERROR_OBJECT {
+			return result
+		}
+	}
+
+	return result
+}
+
+func evalTail(expression ast.Expression, env *object.Environment) object.Object {
+	switch node := expression.(type) {
+	case *ast.CallExpression:
+		return evalTailCall(node, env)
+	case *ast.IfExpression:
+		return evalIf(node, env, evalTailBlock)
+	default:
+		return Eval(expression, env)
+	}
+}
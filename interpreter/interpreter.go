@@ -0,0 +1,104 @@
+// Package interpreter is the entry point for embedding Monkey into Go
+// programs.
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hendrikbursian/monkey-programming-language/diagnostic"
+	"github.com/hendrikbursian/monkey-programming-language/evaluator"
+	"github.com/hendrikbursian/monkey-programming-language/lexer"
+	"github.com/hendrikbursian/monkey-programming-language/object"
+	"github.com/hendrikbursian/monkey-programming-language/parser"
+)
+
+type Options struct {
+	// FreezeGlobals freezes the global environment and every value bound in
+	// it after a module has finished loading. Functions of a frozen module
+	// can then be called from multiple goroutines at the same time.
+	FreezeGlobals bool
+
+	// MaxCallDepth limits the depth of nested function calls. Exceeding it
+	// raises a "maximum recursion depth exceeded" error. Calls in tail
+	// position do not count. Defaults to evaluator.DEFAULT_MAX_CALL_DEPTH.
+	MaxCallDepth int
+
+	// ConditionMode decides whether conditions and the ! operator accept
+	// values that are not booleans. Defaults to object.STRICT_CONDITIONS.
+	ConditionMode object.ConditionMode
+}
+
+type Interpreter struct {
+	options Options
+	env     *object.Environment
+}
+
+func New(options Options) *Interpreter {
+	env := object.NewEnvironment()
+	env.SetMaxCallDepth(options.MaxCallDepth)
+	env.SetConditionMode(options.ConditionMode)
+
+	return &Interpreter{
+		options: options,
+		env:     env,
+	}
+}
+
+// Environment returns the global environment of the interpreter.
+func (interpreter *Interpreter) Environment() *object.Environment {
+	return interpreter.env
+}
+
+// ParseError is returned when a module cannot be parsed.
+type ParseError struct {
+	Errors      []string
+	Diagnostics []*diagnostic.Diagnostic
+}
+
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("parser errors:\n\t%s", strings.Join(err.Errors, "\n\t"))
+}
+
+// LoadModule parses and evaluates input in the global environment and returns
+// the value of the last statement. Runtime errors are returned as
+// *object.Error.
+func (interpreter *Interpreter) LoadModule(input string) (object.Object, error) {
+	return interpreter.LoadFile("", input)
+}
+
+// LoadFile is LoadModule for the source of the named file. Positions of
+// diagnostics and AST nodes refer to the file.
+func (interpreter *Interpreter) LoadFile(name string, input string) (object.Object, error) {
+	p := parser.New(lexer.NewFile(name, input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Errors: p.Errors(), Diagnostics: p.Diagnostics()}
+	}
+
+	result := evaluator.EvalSafely(program, interpreter.env)
+	if err, ok := result.(*object.Error); ok {
+		return nil, err
+	}
+
+	if interpreter.options.FreezeGlobals {
+		interpreter.env.Freeze()
+	}
+
+	return result, nil
+}
+
+// Call calls the global function name with args.
+func (interpreter *Interpreter) Call(name string, args ...object.Object) (object.Object, error) {
+	function, ok := interpreter.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("identifier not found: %s", name)
+	}
+
+	result := evaluator.ApplyFunctionSafely(function, args...)
+	if err, ok := result.(*object.Error); ok {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,287 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hendrikbursian/monkey-programming-language/object"
+)
+
+func TestLoadModule(t *testing.T) {
+	interpreter := New(Options{})
+
+	result, err := interpreter.LoadModule(`let a = 1; a + 1`)
+	if err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	if result.Inspect() != "2" {
+		t.Errorf("result is not 2. got=%s", result.Inspect())
+	}
+
+	if _, err := interpreter.LoadModule(`let = 1`); err == nil {
+		t.Errorf("LoadModule did not return parse error")
+	} else if _, ok := err.(*ParseError); !ok {
+		t.Errorf("err is not *ParseError. got=%T", err)
+	}
+
+	if _, err := interpreter.LoadModule(`1 + true`); err == nil {
+		t.Errorf("LoadModule did not return runtime error")
+	} else if _, ok := err.(*object.Error); !ok {
+		t.Errorf("err is not *object.Error. got=%T", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	interpreter := New(Options{})
+
+	_, err := interpreter.LoadFile("main.mk", "let x = 1;\nlet = 2;")
+	parseError, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("err is not *ParseError. got=%T (%v)", err, err)
+	}
+
+	expected := "expected next token to be 'IDENTIFIER', got '=' instead at main.mk:2:5"
+	if len(parseError.Errors) != 1 || parseError.Errors[0] != expected {
+		t.Errorf("wrong errors. want=%q, got=%q", expected, parseError.Errors)
+	}
+
+	if parseError.Diagnostics[0].Start.File != "main.mk" {
+		t.Errorf("wrong file. got=%q", parseError.Diagnostics[0].Start.File)
+	}
+}
+
+func TestFreezeGlobals(t *testing.T) {
+	interpreter := New(Options{FreezeGlobals: true})
+
+	_, err := interpreter.LoadModule(`
+let items = [1, 2, (3, [4])];
+let table = {"items": items};
+let lookup = fn(key) { table[key].value };
+let add = fn(item) { push(items, item) };
+let log = (fn() { let entries = []; fn(entry) { push(entries, entry) } })();
+`)
+	if err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	if !interpreter.Environment().IsFrozen() {
+		t.Errorf("global environment is not frozen")
+	}
+
+	if _, err := interpreter.Call("add", &object.Integer{Value: 5}); err == nil {
+		t.Errorf("push to frozen global did not fail")
+	} else if err.(*object.Error).Message != "cannot push to frozen array" {
+		t.Errorf("wrong error message. got=%q", err.(*object.Error).Message)
+	}
+
+	if _, err := interpreter.Call("log", &object.Integer{Value: 5}); err == nil {
+		t.Errorf("push to array captured by a frozen closure did not fail")
+	}
+
+	if _, err := interpreter.LoadModule(`let b = 1;`); err == nil {
+		t.Errorf("binding in frozen environment did not fail")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				result, err := interpreter.Call("lookup", &object.String{Value: "items"})
+				if err != nil {
+					t.Errorf("lookup returned error: %s", err)
+					return
+				}
+
+				if result.Inspect() != "[1, 2, (3, [4])]" {
+					t.Errorf("lookup returned wrong result. got=%s", result.Inspect())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCall(t *testing.T) {
+	interpreter := New(Options{})
+
+	if _, err := interpreter.LoadModule(`let add = fn(a, b) { a + b };`); err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []object.Object
+		expected string
+	}{
+		{"add", []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 2}}, "3"},
+		{"missing", nil, "identifier not found: missing"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			result, err := interpreter.Call(tt.name, tt.args...)
+			if err != nil {
+				if err.Error() != tt.expected {
+					t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+				}
+				return
+			}
+
+			if result.Inspect() != tt.expected {
+				t.Errorf("wrong result. want=%q, got=%q", tt.expected, result.Inspect())
+			}
+		})
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	interpreter := New(Options{MaxCallDepth: 100})
+
+	if _, err := interpreter.LoadModule(`let depth = fn(n) { if n > 0 { depth(n - 1); }; n };`); err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	if _, err := interpreter.Call("depth", &object.Integer{Value: 99}); err != nil {
+		t.Errorf("Call returned error: %s", err)
+	}
+
+	_, err := interpreter.Call("depth", &object.Integer{Value: 100})
+	if err == nil || !strings.Contains(err.Error(), "maximum recursion depth exceeded") {
+		t.Errorf("expected recursion error, got=%v", err)
+	}
+}
+
+func TestConditionMode(t *testing.T) {
+	_, err := New(Options{}).LoadModule(`if (1) { 10 }`)
+	if err == nil || !strings.Contains(err.Error(), "condition must be BOOLEAN, got INTEGER") {
+		t.Errorf("expected condition error, got=%v", err)
+	}
+
+	result, err := New(Options{ConditionMode: object.TRUTHY_CONDITIONS}).LoadModule(`if (1) { 10 }`)
+	if err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	if result.Inspect() != "maybe(10)" {
+		t.Errorf("result wrong. got=%s", result.Inspect())
+	}
+
+	interpreter := New(Options{})
+	if _, err := interpreter.LoadModule(`let choose = fn(b) { if b { 1 } else { 2 } };`); err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	result, err = interpreter.Call("choose", &object.Boolean{Value: false})
+	if err != nil {
+		t.Fatalf("Call returned error: %s", err)
+	}
+
+	if result.Inspect() != "maybe(2)" {
+		t.Errorf("result wrong. got=%s", result.Inspect())
+	}
+
+	interpreter.Environment().Set("odd", &object.Builtin{
+		Fn: func(args ...object.Object) (object.Object, error) {
+			return &object.Boolean{Value: args[0].(*object.Integer).Value%2 == 1}, nil
+		},
+	})
+
+	result, err = interpreter.LoadModule(`array(filter([1, 2, 3], odd))`)
+	if err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	if result.Inspect() != "[1, 3]" {
+		t.Errorf("result wrong. got=%s", result.Inspect())
+	}
+}
+
+func TestDeferredCleanup(t *testing.T) {
+	interpreter := New(Options{})
+
+	held := false
+	interpreter.Environment().Set("acquire", &object.Builtin{Fn: func(args ...object.Object) (object.Object, error) {
+		held = true
+		return nil, nil
+	}})
+	interpreter.Environment().Set("release", &object.Builtin{Fn: func(args ...object.Object) (object.Object, error) {
+		held = false
+		return nil, nil
+	}})
+
+	if _, err := interpreter.LoadModule(`let work = fn(fail) { acquire(); defer release(); if fail { missing } else { 1 } };`); err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	for _, fail := range []bool{false, true} {
+		_, err := interpreter.Call("work", &object.Boolean{Value: fail})
+		if (err != nil) != fail {
+			t.Errorf("unexpected error for fail=%t: %v", fail, err)
+		}
+
+		if held {
+			t.Errorf("lock still held for fail=%t", fail)
+		}
+	}
+}
+
+// countdown is an iterable implemented in Go.
+type countdown struct {
+	from int64
+}
+
+func (c *countdown) Type() object.ObjectType { return "COUNTDOWN" }
+func (c *countdown) Inspect() string         { return fmt.Sprintf("countdown(%d)", c.from) }
+func (c *countdown) Iterate() object.Iterator {
+	n := c.from
+	return object.IteratorFunc(func() (object.Object, bool) {
+		if n <= 0 {
+			return nil, false
+		}
+
+		n--
+		return &object.Integer{Value: n + 1}, true
+	})
+}
+
+func TestGoIterables(t *testing.T) {
+	interpreter := New(Options{})
+	interpreter.Environment().Set("countdown", &countdown{from: 3})
+
+	result, err := interpreter.LoadModule(`
+let out = [];
+for n in countdown { push(out, n * 10) };
+(out, array(map(countdown, fn(n) { n + 1 })))
+`)
+	if err != nil {
+		t.Fatalf("LoadModule returned error: %s", err)
+	}
+
+	if result.Inspect() != "([30, 20, 10], [4, 3, 2])" {
+		t.Errorf("result wrong. got=%s", result.Inspect())
+	}
+}
+
+func TestPanickingBuiltin(t *testing.T) {
+	interpreter := New(Options{})
+	interpreter.Environment().Set("boom", &object.Builtin{Fn: func(args ...object.Object) (object.Object, error) {
+		panic("boom")
+	}})
+
+	_, err := interpreter.LoadModule(`boom()`)
+	if err == nil || !strings.Contains(err.Error(), "internal error: boom") {
+		t.Errorf("expected internal error, got=%v", err)
+	}
+
+	_, err = interpreter.Call("boom")
+	if err == nil || !strings.Contains(err.Error(), "internal error: boom") {
+		t.Errorf("expected internal error, got=%v", err)
+	}
+}
@@ -1,21 +1,30 @@
 package lexer
 
 import (
-	"fmt"
+	"github.com/hendrikbursian/monkey-programming-language/diagnostic"
 	"github.com/hendrikbursian/monkey-programming-language/token"
 )
 
 type Lexer struct {
+	file         string
 	input        string
 	position     int
 	readPosition int
 	char         byte
 	line         int
 	column       int
+	diagnostics  []*diagnostic.Diagnostic
 }
 
 func New(input string) *Lexer {
+	return NewFile("", input)
+}
+
+// NewFile returns a lexer for the source of the named file. The name is set
+// on all tokens.
+func NewFile(file string, input string) *Lexer {
 	lexer := &Lexer{
+		file:  file,
 		input: input,
 		line:  1,
 	}
@@ -23,6 +32,30 @@ func New(input string) *Lexer {
 	return lexer
 }
 
+// Diagnostics returns the problems found in the tokens read so far.
+func (lexer *Lexer) Diagnostics() []*diagnostic.Diagnostic {
+	return lexer.diagnostics
+}
+
+// TokenSpan returns the span of the token of source that starts at
+// line:column. It is used for errors that only know where they start, like
+// runtime errors. Positions that are not at the start of a token get a span of
+// length 1.
+func TokenSpan(source string, line, column int) diagnostic.Span {
+	lexer := New(source)
+
+	for {
+		tok := lexer.NextToken()
+		if tok.Type == token.EOF || tok.Line > line || (tok.Line == line && tok.Column > column) {
+			return diagnostic.Span{Line: line, Column: column, Length: 1}
+		}
+
+		if tok.Line == line && tok.Column == column {
+			return diagnostic.SpanOf(tok)
+		}
+	}
+}
+
 func newToken(tokenType token.TokenType, char byte, line int, column int) token.Token {
 	return token.Token{
 		Type:    tokenType,
@@ -33,10 +66,40 @@ func newToken(tokenType token.TokenType, char byte, line int, column int) token.
 }
 
 func (lexer *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	lexer.skipWhitespace()
 
+	offset := lexer.position
+	tok := lexer.readToken()
+
+	tok.File = lexer.file
+	tok.Offset = offset
+	if tok.Type == token.EOF {
+		tok.Offset = len(lexer.input)
+		tok.EndLine, tok.EndColumn, tok.EndOffset = tok.Line, tok.Column, tok.Offset
+		return tok
+	}
+	tok.EndLine, tok.EndColumn, tok.EndOffset = lexer.line, lexer.column, lexer.position
+	if tok.EndOffset > len(lexer.input) {
+		// Unterminated strings end past the end of the input.
+		tok.EndColumn -= tok.EndOffset - len(lexer.input)
+		tok.EndOffset = len(lexer.input)
+	}
+
+	switch tok.Type {
+	case token.ILLEGAL:
+		lexer.diagnostics = append(lexer.diagnostics, diagnostic.AtToken(diagnostic.ERROR, diagnostic.ILLEGAL_CHARACTER, tok, "illegal character %q", tok.Literal))
+	case token.STRING:
+		if raw := lexer.input[tok.Offset:tok.EndOffset]; len(raw) < 2 || raw[len(raw)-1] != '"' {
+			lexer.diagnostics = append(lexer.diagnostics, diagnostic.AtToken(diagnostic.ERROR, diagnostic.UNTERMINATED_STRING, tok, "unterminated string").WithHint("add a closing '\"'"))
+		}
+	}
+
+	return tok
+}
+
+func (lexer *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	switch lexer.char {
 	case ';':
 		tok = newToken(token.SEMICOLON, lexer.char, lexer.line, lexer.column)
@@ -47,6 +110,14 @@ func (lexer *Lexer) NextToken() token.Token {
 			tok.Line = lexer.line
 			tok.Column = lexer.column
 
+			char := lexer.char
+			lexer.readChar()
+			tok.Literal = string(char) + string(lexer.char)
+		} else if lexer.peekChar() == '>' {
+			tok.Type = token.ARROW
+			tok.Line = lexer.line
+			tok.Column = lexer.column
+
 			char := lexer.char
 			lexer.readChar()
 			tok.Literal = string(char) + string(lexer.char)
@@ -88,16 +159,67 @@ func (lexer *Lexer) NextToken() token.Token {
 		tok = newToken(token.ASTERISK, lexer.char, lexer.line, lexer.column)
 		break
 	case '<':
-		tok = newToken(token.LESS_THAN, lexer.char, lexer.line, lexer.column)
+		if lexer.peekChar() == '<' {
+			tok.Type = token.COMPOSE_LEFT
+			tok.Line = lexer.line
+			tok.Column = lexer.column
+
+			char := lexer.char
+			lexer.readChar()
+			tok.Literal = string(char) + string(lexer.char)
+		} else {
+			tok = newToken(token.LESS_THAN, lexer.char, lexer.line, lexer.column)
+		}
 		break
 	case '>':
-		tok = newToken(token.GREATER_THAN, lexer.char, lexer.line, lexer.column)
+		if lexer.peekChar() == '>' {
+			tok.Type = token.COMPOSE_RIGHT
+			tok.Line = lexer.line
+			tok.Column = lexer.column
+
+			char := lexer.char
+			lexer.readChar()
+			tok.Literal = string(char) + string(lexer.char)
+		} else {
+			tok = newToken(token.GREATER_THAN, lexer.char, lexer.line, lexer.column)
+		}
+		break
+	case '|':
+		if lexer.peekChar() == '>' {
+			tok.Type = token.PIPE
+			tok.Line = lexer.line
+			tok.Column = lexer.column
+
+			char := lexer.char
+			lexer.readChar()
+			tok.Literal = string(char) + string(lexer.char)
+		} else {
+			tok = newToken(token.BAR, lexer.char, lexer.line, lexer.column)
+		}
+		break
+	case '&':
+		tok = newToken(token.AMPERSAND, lexer.char, lexer.line, lexer.column)
 		break
 	case ':':
 		tok = newToken(token.COLON, lexer.char, lexer.line, lexer.column)
 		break
 	case '.':
-		tok = newToken(token.DOT, lexer.char, lexer.line, lexer.column)
+		if lexer.peekChar() == '.' {
+			tok.Type = token.RANGE
+			tok.Line = lexer.line
+			tok.Column = lexer.column
+
+			lexer.readChar()
+			tok.Literal = ".."
+
+			if lexer.peekChar() == '<' {
+				lexer.readChar()
+				tok.Type = token.RANGE_EXCLUSIVE
+				tok.Literal = "..<"
+			}
+		} else {
+			tok = newToken(token.DOT, lexer.char, lexer.line, lexer.column)
+		}
 		break
 	case '!':
 		if lexer.peekChar() == '=' {
@@ -141,7 +263,6 @@ func (lexer *Lexer) NextToken() token.Token {
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, lexer.char, lexer.line, lexer.column)
-			fmt.Printf("WARN: '%s'(byte: %d) at %d:%d is not a legal token", tok.Literal, tok.Literal[0], tok.Line, tok.Column)
 		}
 	}
 
@@ -168,6 +289,11 @@ func (lexer *Lexer) readString() string {
 		if lexer.char == '"' || lexer.char == 0 {
 			break
 		}
+
+		if lexer.char == '\n' {
+			lexer.line++
+			lexer.column = 0
+		}
 	}
 
 	return lexer.input[position:lexer.position]
@@ -38,6 +38,10 @@ true != false
 { "hello": "world", 2: { true: "test" }}
 
 ["test"][0].hasValue
+x in a | b & c
+a[1:-1] 1..10 0..<n
+xs |> f >> g << h
+x => x
 `
 	tests := []struct {
 		expectedType    token.TokenType
@@ -173,7 +177,41 @@ true != false
 		{token.DOT, ".", 29, 12},
 		{token.IDENTIFIER, "hasValue", 29, 13},
 
-		{token.EOF, "", 30, 1},
+		// x in a | b & c
+		{token.IDENTIFIER, "x", 30, 1},
+		{token.IN, "in", 30, 3},
+		{token.IDENTIFIER, "a", 30, 6},
+		{token.BAR, "|", 30, 8},
+		{token.IDENTIFIER, "b", 30, 10},
+		{token.AMPERSAND, "&", 30, 12},
+		{token.IDENTIFIER, "c", 30, 14},
+
+		// a[1:-1] 1..10 0..<n
+		{token.IDENTIFIER, "a", 31, 1},
+		{token.LEFT_SQUARE_BRACKET, "[", 31, 2},
+		{token.INTEGER, "1", 31, 3},
+		{token.COLON, ":", 31, 4},
+		{token.MINUS, "-", 31, 5},
+		{token.INTEGER, "1", 31, 6},
+		{token.RIGHT_SQUARE_BRACKET, "]", 31, 7},
+		{token.INTEGER, "1", 31, 9},
+		{token.RANGE, "..", 31, 10},
+		{token.INTEGER, "10", 31, 12},
+		{token.INTEGER, "0", 31, 15},
+		{token.RANGE_EXCLUSIVE, "..<", 31, 16},
+		{token.IDENTIFIER, "n", 31, 19},
+		{token.IDENTIFIER, "xs", 32, 1},
+		{token.PIPE, "|>", 32, 4},
+		{token.IDENTIFIER, "f", 32, 7},
+		{token.COMPOSE_RIGHT, ">>", 32, 9},
+		{token.IDENTIFIER, "g", 32, 12},
+		{token.COMPOSE_LEFT, "<<", 32, 14},
+		{token.IDENTIFIER, "h", 32, 17},
+		{token.IDENTIFIER, "x", 33, 1},
+		{token.ARROW, "=>", 33, 3},
+		{token.IDENTIFIER, "x", 33, 6},
+
+		{token.EOF, "", 34, 1},
 	}
 
 	l := New(code)
@@ -200,3 +238,38 @@ true != false
 		})
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	l := NewFile("main.mk", "let s = \"a\nb\";\n  x")
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{File: "main.mk", Offset: 0, Line: 1, Column: 1}, token.Position{File: "main.mk", Offset: 3, Line: 1, Column: 4}},
+		{token.IDENTIFIER, token.Position{File: "main.mk", Offset: 4, Line: 1, Column: 5}, token.Position{File: "main.mk", Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{File: "main.mk", Offset: 6, Line: 1, Column: 7}, token.Position{File: "main.mk", Offset: 7, Line: 1, Column: 8}},
+		{token.STRING, token.Position{File: "main.mk", Offset: 8, Line: 1, Column: 9}, token.Position{File: "main.mk", Offset: 13, Line: 2, Column: 3}},
+		{token.SEMICOLON, token.Position{File: "main.mk", Offset: 13, Line: 2, Column: 3}, token.Position{File: "main.mk", Offset: 14, Line: 2, Column: 4}},
+		{token.IDENTIFIER, token.Position{File: "main.mk", Offset: 17, Line: 3, Column: 3}, token.Position{File: "main.mk", Offset: 18, Line: 3, Column: 4}},
+		{token.EOF, token.Position{File: "main.mk", Offset: 18, Line: 3, Column: 4}, token.Position{File: "main.mk", Offset: 18, Line: 3, Column: 4}},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			if tok.Type != tt.expectedType {
+				t.Errorf("wrong token type. want=%s, got=%s", tt.expectedType, tok.Type)
+			}
+
+			if tok.Pos() != tt.expectedPos {
+				t.Errorf("wrong position. want=%+v, got=%+v", tt.expectedPos, tok.Pos())
+			}
+
+			if tok.End() != tt.expectedEnd {
+				t.Errorf("wrong end. want=%+v, got=%+v", tt.expectedEnd, tok.End())
+			}
+		})
+	}
+}
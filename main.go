@@ -1,15 +1,28 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
 	"strings"
 
+	"github.com/hendrikbursian/monkey-programming-language/evaluator"
+	"github.com/hendrikbursian/monkey-programming-language/object"
 	"github.com/hendrikbursian/monkey-programming-language/repl"
 )
 
 func main() {
+	maxCallDepth := flag.Int("max-call-depth", evaluator.DEFAULT_MAX_CALL_DEPTH, "maximum depth of nested function calls")
+	color := flag.Bool("color", false, "print errors with ANSI colors")
+	truthy := flag.Bool("truthy", false, "accept values that are not booleans as conditions")
+	flag.Parse()
+
+	conditionMode := object.STRICT_CONDITIONS
+	if *truthy {
+		conditionMode = object.TRUTHY_CONDITIONS
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -19,5 +32,5 @@ func main() {
 
 	fmt.Printf("Hello %s! This is the Monkey programming language! \n", name)
 	fmt.Printf("Type commands here!\n\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, repl.Options{MaxCallDepth: *maxCallDepth, Color: *color, ConditionMode: conditionMode})
 }
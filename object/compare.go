@@ -0,0 +1,193 @@
+package object
+
+import "strings"
+
+// objectPair is used to remember which comparisons are already in progress,
+// so that self-referential arrays and hashes do not recurse forever.
+type objectPair struct {
+	a, b Object
+}
+
+// Equal reports whether a and b are structurally equal. Arrays, tuples,
+// hashes, sets and maybes are compared by content, functions and builtins by
+// identity. Values of different types are never equal.
+func Equal(a, b Object) bool {
+	return equal(a, b, map[objectPair]bool{})
+}
+
+func equal(a, b Object, visiting map[objectPair]bool) bool {
+	if a == b {
+		return true
+	}
+
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return false
+	}
+
+	// A comparison that is already in progress further up the stack is
+	// assumed to hold; any difference is reported by that comparison.
+	pair := objectPair{a, b}
+	if visiting[pair] {
+		return true
+	}
+	visiting[pair] = true
+	defer delete(visiting, pair)
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *Range:
+		other := b.(*Range)
+		if a.Len() == 0 || other.Len() == 0 {
+			return a.Len() == other.Len()
+		}
+		return a.Start == other.Start && a.Stop == other.Stop
+	case *Array:
+		return equalElements(a.Elements, b.(*Array).Elements, visiting)
+	case *Tuple:
+		return equalElements(a.Elements, b.(*Tuple).Elements, visiting)
+	case *Vector:
+		return equalElements(a.Elements(), b.(*Vector).Elements(), visiting)
+	case *Unit:
+		return true
+	case *Maybe:
+		return equal(a.Value, b.(*Maybe).Value, visiting)
+	case *Dict:
+		other := b.(*Dict)
+		if a.Len() != other.Len() {
+			return false
+		}
+
+		for _, pair := range a.Pairs() {
+			otherPair, ok := other.Get(pair.Key.(Hashable).HashKey())
+			if !ok || !equal(pair.Value, otherPair.Value, visiting) {
+				return false
+			}
+		}
+
+		return true
+	case *Set:
+		other := b.(*Set)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+
+		for key := range a.Elements {
+			if _, ok := other.Elements[key]; !ok {
+				return false
+			}
+		}
+
+		return true
+	case *Hash:
+		other := b.(*Hash)
+		if len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+
+		for key, pair := range a.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !equal(pair.Value, otherPair.Value, visiting) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return false
+	}
+}
+
+func equalElements(a, b []Object, visiting map[objectPair]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !equal(a[i], b[i], visiting) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Compare orders a and b. It returns -1, 0 or 1 and true if the values are
+// comparable. Integers are ordered numerically, strings lexicographically,
+// false before true, and arrays, tuples and vectors lexicographically by their
+// elements.
+func Compare(a, b Object) (int, bool) {
+	return compare(a, b, map[objectPair]bool{})
+}
+
+func compare(a, b Object, visiting map[objectPair]bool) (int, bool) {
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return 0, false
+	}
+
+	pair := objectPair{a, b}
+	if visiting[pair] {
+		return 0, true
+	}
+	visiting[pair] = true
+	defer delete(visiting, pair)
+
+	switch a := a.(type) {
+	case *Integer:
+		other := b.(*Integer).Value
+		switch {
+		case a.Value < other:
+			return -1, true
+		case a.Value > other:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case *String:
+		return strings.Compare(a.Value, b.(*String).Value), true
+	case *Boolean:
+		other := b.(*Boolean).Value
+		switch {
+		case a.Value == other:
+			return 0, true
+		case other:
+			return -1, true
+		default:
+			return 1, true
+		}
+	case *Array:
+		return compareElements(a.Elements, b.(*Array).Elements, visiting)
+	case *Tuple:
+		return compareElements(a.Elements, b.(*Tuple).Elements, visiting)
+	case *Vector:
+		return compareElements(a.Elements(), b.(*Vector).Elements(), visiting)
+	default:
+		return 0, false
+	}
+}
+
+func compareElements(a, b []Object, visiting map[objectPair]bool) (int, bool) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		result, ok := compare(a[i], b[i], visiting)
+		if !ok {
+			return 0, false
+		}
+
+		if result != 0 {
+			return result, true
+		}
+	}
+
+	switch {
+	case len(a) < len(b):
+		return -1, true
+	case len(a) > len(b):
+		return 1, true
+	default:
+		return 0, true
+	}
+}
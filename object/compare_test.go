@@ -0,0 +1,43 @@
+package object
+
+import (
+	"testing"
+)
+
+func TestEqualCyclicArrays(t *testing.T) {
+	a := &Array{}
+	a.Elements = []Object{&Integer{Value: 1}, a}
+	b := &Array{}
+	b.Elements = []Object{&Integer{Value: 1}, b}
+	c := &Array{}
+	c.Elements = []Object{&Integer{Value: 2}, c}
+
+	if !Equal(a, b) {
+		t.Errorf("self-referential arrays with same content are not equal")
+	}
+	if Equal(a, c) {
+		t.Errorf("self-referential arrays with different content are equal")
+	}
+
+	if _, ok := Compare(a, b); !ok {
+		t.Errorf("self-referential arrays are not comparable")
+	}
+	if result, _ := Compare(a, c); result != -1 {
+		t.Errorf("wrong ordering of self-referential arrays. want=%d, got=%d", -1, result)
+	}
+}
+
+func TestEqualHashesIgnoresOrder(t *testing.T) {
+	one, two := &String{Value: "one"}, &String{Value: "two"}
+
+	a := NewHash()
+	a.Set(one.HashKey(), HashPair{Key: one, Value: &Integer{Value: 1}})
+	a.Set(two.HashKey(), HashPair{Key: two, Value: &Array{Elements: []Object{&Integer{Value: 2}}}})
+	b := NewHash()
+	b.Set(two.HashKey(), HashPair{Key: two, Value: &Array{Elements: []Object{&Integer{Value: 2}}}})
+	b.Set(one.HashKey(), HashPair{Key: one, Value: &Integer{Value: 1}})
+
+	if !Equal(a, b) {
+		t.Errorf("hashes with same pairs in different order are not equal")
+	}
+}
@@ -0,0 +1,170 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// inspect renders obj like Inspect. Containers that are already being
+// rendered further up are printed as `[...]`, `{...}` or `(...)` so that
+// self-referential values terminate.
+func inspect(obj Object, visiting map[Object]bool) string {
+	if obj == nil {
+		return "nil"
+	}
+
+	if !isContainer(obj) {
+		return obj.Inspect()
+	}
+
+	if visiting[obj] {
+		return cycleMarker(obj)
+	}
+	visiting[obj] = true
+	defer delete(visiting, obj)
+
+	open, close := brackets(obj)
+
+	var out bytes.Buffer
+	out.WriteString(open)
+	out.WriteString(strings.Join(inspectParts(obj, visiting), ", "))
+	if tuple, ok := obj.(*Tuple); ok && len(tuple.Elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(close)
+
+	return out.String()
+}
+
+func isContainer(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Array, *Tuple, *Hash, *Vector, *Dict:
+		return true
+	case *Maybe:
+		return obj.Value != nil
+	default:
+		return false
+	}
+}
+
+func brackets(obj Object) (string, string) {
+	switch obj.(type) {
+	case *Array:
+		return "[", "]"
+	case *Tuple:
+		return "(", ")"
+	case *Hash:
+		return "{", "}"
+	case *Vector:
+		return "vector([", "])"
+	case *Dict:
+		return "dict({", "})"
+	default:
+		return "maybe(", ")"
+	}
+}
+
+func cycleMarker(obj Object) string {
+	open, close := brackets(obj)
+	return open + "..." + close
+}
+
+// inspectParts returns the rendered elements (or key-value pairs) of a
+// container.
+func inspectParts(obj Object, visiting map[Object]bool) []string {
+	parts := []string{}
+
+	switch obj := obj.(type) {
+	case *Array:
+		for _, element := range obj.Elements {
+			parts = append(parts, inspect(element, visiting))
+		}
+	case *Tuple:
+		for _, element := range obj.Elements {
+			parts = append(parts, inspect(element, visiting))
+		}
+	case *Hash:
+		for _, pair := range obj.Ordered() {
+			parts = append(parts, fmt.Sprintf("%s: %s", inspect(pair.Key, visiting), inspect(pair.Value, visiting)))
+		}
+	case *Vector:
+		for _, element := range obj.Elements() {
+			parts = append(parts, inspect(element, visiting))
+		}
+	case *Dict:
+		for _, pair := range obj.Pairs() {
+			parts = append(parts, fmt.Sprintf("%s: %s", inspect(pair.Key, visiting), inspect(pair.Value, visiting)))
+		}
+	case *Maybe:
+		parts = append(parts, inspect(obj.Value, visiting))
+	}
+
+	return parts
+}
+
+// Pretty renders obj like Inspect, but breaks collections that
+// do not fit into width columns over multiple indented lines.
+func Pretty(obj Object, width int) string {
+	var out bytes.Buffer
+	pretty(&out, obj, 0, 0, width, map[Object]bool{})
+	return out.String()
+}
+
+// pretty writes obj to out. indent is the indentation of the current line and
+// column the number of characters already written to it.
+func pretty(out *bytes.Buffer, obj Object, indent, column, width int, visiting map[Object]bool) {
+	flat := inspect(obj, visiting)
+	if column+len(flat) <= width || !isContainer(obj) || visiting[obj] {
+		out.WriteString(flat)
+		return
+	}
+
+	visiting[obj] = true
+	defer delete(visiting, obj)
+
+	open, close := brackets(obj)
+
+	if maybe, ok := obj.(*Maybe); ok {
+		out.WriteString(open)
+		pretty(out, maybe.Value, indent, column+len(open), width, visiting)
+		out.WriteString(close)
+		return
+	}
+
+	out.WriteString(open)
+	out.WriteString("\n")
+
+	writeElement := func(prefix string, element Object) {
+		out.WriteString(strings.Repeat(" ", indent+2))
+		out.WriteString(prefix)
+		pretty(out, element, indent+2, indent+2+len(prefix), width, visiting)
+		out.WriteString(",\n")
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		for _, element := range obj.Elements {
+			writeElement("", element)
+		}
+	case *Tuple:
+		for _, element := range obj.Elements {
+			writeElement("", element)
+		}
+	case *Hash:
+		for _, pair := range obj.Ordered() {
+			writeElement(inspect(pair.Key, visiting)+": ", pair.Value)
+		}
+	case *Vector:
+		for _, element := range obj.Elements() {
+			writeElement("", element)
+		}
+	case *Dict:
+		for _, pair := range obj.Pairs() {
+			writeElement(inspect(pair.Key, visiting)+": ", pair.Value)
+		}
+	}
+
+	out.WriteString(strings.Repeat(" ", indent))
+	out.WriteString(close)
+}
@@ -0,0 +1,80 @@
+package object
+
+import (
+	"testing"
+)
+
+func TestInspectCycles(t *testing.T) {
+	array := &Array{}
+	array.Elements = []Object{&Integer{Value: 1}, array}
+
+	key := &String{Value: "self"}
+	hash := NewHash()
+	hash.Set(key.HashKey(), HashPair{Key: key, Value: hash})
+
+	tuple := &Tuple{Elements: []Object{array, &Maybe{Value: array}}}
+	nested := &Array{Elements: []Object{tuple, tuple}}
+
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{array, "[1, [...]]"},
+		{hash, `{"self": {...}}`},
+		{tuple, "([1, [...]], maybe([1, [...]]))"},
+		{nested, "[([1, [...]], maybe([1, [...]])), ([1, [...]], maybe([1, [...]]))]"},
+		{&Maybe{}, "maybe([no value])"},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}}}, "(1,)"},
+	}
+
+	for _, tt := range tests {
+		if tt.obj.Inspect() != tt.expected {
+			t.Errorf("Inspect() wrong. want=%q, got=%q", tt.expected, tt.obj.Inspect())
+		}
+	}
+}
+
+func TestPretty(t *testing.T) {
+	one, two := &String{Value: "one"}, &String{Value: "two"}
+	hash := NewHash()
+	hash.Set(one.HashKey(), HashPair{Key: one, Value: &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}})
+	hash.Set(two.HashKey(), HashPair{Key: two, Value: &Tuple{Elements: []Object{&String{Value: "a long string"}, &Integer{Value: 3}}}})
+
+	array := &Array{Elements: []Object{hash, &Integer{Value: 4}}}
+	array.Elements = append(array.Elements, array)
+
+	tests := []struct {
+		obj      Object
+		width    int
+		expected string
+	}{
+		{array, 80, `[{"one": [1, 2], "two": ("a long string", 3)}, 4, [...]]`},
+		{array, 50, `[
+  {"one": [1, 2], "two": ("a long string", 3)},
+  4,
+  [...],
+]`},
+		{array, 34, `[
+  {
+    "one": [1, 2],
+    "two": ("a long string", 3),
+  },
+  4,
+  [...],
+]`},
+		{&Maybe{Value: hash}, 20, `maybe({
+  "one": [1, 2],
+  "two": (
+    "a long string",
+    3,
+  ),
+})`},
+	}
+
+	for _, tt := range tests {
+		actual := Pretty(tt.obj, tt.width)
+		if actual != tt.expected {
+			t.Errorf("Pretty(%d) wrong. want=\n%s\ngot=\n%s", tt.width, tt.expected, actual)
+		}
+	}
+}
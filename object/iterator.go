@@ -0,0 +1,78 @@
+package object
+
+// Iterator produces the values of a sequence one at a time. Next returns false
+// once the sequence is exhausted. An *Error value aborts the iteration.
+type Iterator interface {
+	Next() (Object, bool)
+}
+
+// Iterable is implemented by all objects that can be consumed by `for` loops
+// and sequence builtins. Embedders can implement it on their own objects.
+type Iterable interface {
+	Iterate() Iterator
+}
+
+// IteratorFunc adapts a plain function to the Iterator interface.
+type IteratorFunc func() (Object, bool)
+
+func (fn IteratorFunc) Next() (Object, bool) { return fn() }
+
+// Sequence is a lazy, single-pass sequence of values. Consuming it, e.g. with
+// a `for` loop, advances the underlying iterator.
+type Sequence struct {
+	Iterator Iterator
+}
+
+func (s *Sequence) Type() ObjectType  { return SEQUENCE_OBJECT }
+func (s *Sequence) Inspect() string   { return "sequence" }
+func (s *Sequence) Iterate() Iterator { return s.Iterator }
+func (a *Array) Iterate() Iterator    { return iterateSlice(a.Elements) }
+func (t *Tuple) Iterate() Iterator    { return iterateSlice(t.Elements) }
+func (s *Set) Iterate() Iterator      { return iterateSlice(s.Sorted()) }
+func (v *Vector) Iterate() Iterator   { return iterateIndex(v.Len(), v.Get) }
+func (r *Range) Iterate() Iterator {
+	return iterateIndex(r.Len(), func(i int) Object { return r.Get(i) })
+}
+
+// Iterate yields the characters of the string, not its bytes.
+func (s *String) Iterate() Iterator {
+	runes := []rune(s.Value)
+	return iterateIndex(len(runes), func(i int) Object { return &String{Value: string(runes[i])} })
+}
+
+// Iterate yields the keys of the hash in insertion order.
+func (h *Hash) Iterate() Iterator {
+	keys := make([]Object, 0, len(h.Keys))
+	for _, pair := range h.Ordered() {
+		keys = append(keys, pair.Key)
+	}
+
+	return iterateSlice(keys)
+}
+
+// Iterate yields the keys of the dict in trie order.
+func (d *Dict) Iterate() Iterator {
+	keys := make([]Object, 0, d.Len())
+	for _, pair := range d.Pairs() {
+		keys = append(keys, pair.Key)
+	}
+
+	return iterateSlice(keys)
+}
+
+func iterateSlice(elements []Object) Iterator {
+	return iterateIndex(len(elements), func(i int) Object { return elements[i] })
+}
+
+func iterateIndex(length int, get func(int) Object) Iterator {
+	i := 0
+
+	return IteratorFunc(func() (Object, bool) {
+		if i >= length {
+			return nil, false
+		}
+
+		i++
+		return get(i - 1), true
+	})
+}
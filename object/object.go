@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/hendrikbursian/monkey-programming-language/ast"
 )
@@ -22,18 +24,94 @@ const (
 	ARRAY_OBJECT        = "ARRAY"
 	HASH_OBJECT         = "HASH"
 	MAYBE_OBJECT        = "MAYBE"
+	TUPLE_OBJECT        = "TUPLE"
+	SET_OBJECT          = "SET"
+	VECTOR_OBJECT       = "VECTOR"
+	DICT_OBJECT         = "DICT"
+	RANGE_OBJECT        = "RANGE"
+	SEQUENCE_OBJECT     = "SEQUENCE"
+	UNIT_OBJECT         = "UNIT"
 )
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store     map[string]Object
+	constants map[string]bool
+	outer     *Environment
+	frozen    bool
+
+	// valuesFrozen is set once the values bound in env have been frozen.
+	valuesFrozen bool
+
+	// yield is set on the environment of a running generator call.
+	yield func(Object) bool
+
+	// call is set on the environment of a function call.
+	call bool
+
+	// frame is set on the environment of a function call.
+	frame *Frame
+
+	// deferred collects the code deferred by the function call that env is
+	// the environment of.
+	deferred []func() Object
+
+	maxCallDepth  int
+	conditionMode ConditionMode
+}
+
+// ConditionMode decides how conditions of if expressions and comprehensions
+// and the ! operator treat values that are not booleans.
+type ConditionMode int
+
+const (
+	// STRICT_CONDITIONS, the default, rejects values that are not booleans
+	// with an error.
+	STRICT_CONDITIONS ConditionMode = iota + 1
+
+	// TRUTHY_CONDITIONS accepts any value. Zero, empty strings, empty
+	// collections and ranges, empty maybes and unit are false, every other
+	// value is true.
+	TRUTHY_CONDITIONS
+)
+
+// Frame is an entry of the call stack: the call of Function at Line:Column.
+// Frames are never modified, so a call stack can be shared by all calls made
+// from it and captured without copying.
+type Frame struct {
+	Function string
+	Line     int
+	Column   int
+	Parent   *Frame
+	Depth    int
+}
+
+// Push returns a new frame for a call made from frame, which may be nil for
+// calls made from the top level.
+func (frame *Frame) Push(function string, line, column int) *Frame {
+	depth := 1
+	if frame != nil {
+		depth = frame.Depth + 1
+	}
+
+	return &Frame{Function: function, Line: line, Column: column, Parent: frame, Depth: depth}
+}
+
+// Stack returns the call stack ending in frame, innermost call first.
+func (frame *Frame) Stack() []StackFrame {
+	stack := []StackFrame{}
+	for ; frame != nil; frame = frame.Parent {
+		stack = append(stack, StackFrame{Function: frame.Function, Line: frame.Line, Column: frame.Column})
+	}
+
+	return stack
 }
 
 func NewEnvironment() *Environment {
 	store := make(map[string]Object)
 	return &Environment{
-		store: store,
-		outer: nil,
+		store:     store,
+		constants: make(map[string]bool),
+		outer:     nil,
 	}
 }
 
@@ -43,6 +121,14 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// NewCallEnvironment returns the environment of a call of a function defined
+// in outer.
+func NewCallEnvironment(outer *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.call = true
+	return env
+}
+
 func (env *Environment) Get(name string) (Object, bool) {
 	object, ok := env.store[name]
 	if !ok && env.outer != nil {
@@ -52,11 +138,159 @@ func (env *Environment) Get(name string) (Object, bool) {
 	return object, ok
 }
 
+// Names returns the names bound in env and its outer environments.
+func (env *Environment) Names() []string {
+	names := []string{}
+	for ; env != nil; env = env.outer {
+		for name := range env.store {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
 func (env *Environment) Set(name string, value Object) Object {
 	env.store[name] = value
 	return value
 }
 
+// SetConstant binds name to value and marks the binding as constant.
+func (env *Environment) SetConstant(name string, value Object) Object {
+	env.constants[name] = true
+	return env.Set(name, value)
+}
+
+// IsConstant reports whether name is bound as a constant in this environment.
+// Constants of outer environments may be shadowed and are not considered.
+func (env *Environment) IsConstant(name string) bool {
+	return env.constants[name]
+}
+
+// Freeze deep-freezes all values bound in the environment and rejects any
+// further bindings. A frozen environment can be read from multiple goroutines
+// at the same time.
+func (env *Environment) Freeze() {
+	env.frozen = true
+	env.freezeValues()
+}
+
+// freezeValues deep-freezes all values bound in the environment. Unlike
+// Freeze, it still accepts new bindings.
+func (env *Environment) freezeValues() {
+	if env.valuesFrozen {
+		return
+	}
+	env.valuesFrozen = true
+
+	for _, value := range env.store {
+		Freeze(value)
+	}
+}
+
+// SetYield installs the function that a yield statement evaluated in this
+// environment, or in one enclosed by it, hands its value to. The function
+// returns false if the generator has been abandoned and has to stop.
+func (env *Environment) SetYield(yield func(Object) bool) {
+	env.yield = yield
+}
+
+// Yield returns the yield function of the generator call env belongs to. It
+// does not look past the innermost function call, so functions called by a
+// generator cannot yield for it.
+func (env *Environment) Yield() (func(Object) bool, bool) {
+	for ; env != nil; env = env.outer {
+		if env.yield != nil {
+			return env.yield, true
+		}
+
+		if env.call {
+			break
+		}
+	}
+
+	return nil, false
+}
+
+// SetFrame marks env as the environment of the call described by frame.
+func (env *Environment) SetFrame(frame *Frame) {
+	env.frame = frame
+}
+
+// Frame returns the frame of the innermost function call env belongs to, or
+// nil at the top level.
+func (env *Environment) Frame() *Frame {
+	for ; env != nil; env = env.outer {
+		if env.frame != nil {
+			return env.frame
+		}
+	}
+
+	return nil
+}
+
+// Defer schedules f to run when the innermost function call env belongs to
+// returns. It returns false at the top level.
+func (env *Environment) Defer(f func() Object) bool {
+	for ; env != nil; env = env.outer {
+		if env.frame != nil {
+			env.deferred = append(env.deferred, f)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Deferred removes and returns the code deferred in the function call env is
+// the environment of, in the order it was deferred.
+func (env *Environment) Deferred() []func() Object {
+	deferred := env.deferred
+	env.deferred = nil
+
+	return deferred
+}
+
+// SetMaxCallDepth limits the depth of calls of functions defined in env or
+// in environments enclosed by it.
+func (env *Environment) SetMaxCallDepth(depth int) {
+	env.maxCallDepth = depth
+}
+
+// MaxCallDepth returns the innermost call depth limit set on env or one of its
+// outer environments, or 0 if none is set.
+func (env *Environment) MaxCallDepth() int {
+	for ; env != nil; env = env.outer {
+		if env.maxCallDepth != 0 {
+			return env.maxCallDepth
+		}
+	}
+
+	return 0
+}
+
+// SetConditionMode sets the condition mode of code evaluated in env or in
+// environments enclosed by it.
+func (env *Environment) SetConditionMode(mode ConditionMode) {
+	env.conditionMode = mode
+}
+
+// ConditionMode returns the innermost condition mode set on env or one of its
+// outer environments, or STRICT_CONDITIONS if none is set.
+func (env *Environment) ConditionMode() ConditionMode {
+	for ; env != nil; env = env.outer {
+		if env.conditionMode != 0 {
+			return env.conditionMode
+		}
+	}
+
+	return STRICT_CONDITIONS
+}
+
+func (env *Environment) IsFrozen() bool {
+	return env.frozen
+}
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
@@ -71,11 +305,13 @@ type Hashable interface {
 	HashKey() HashKey
 }
 
-var hashKeyCache map[any]HashKey = map[any]HashKey{}
+// hashKeyCache is shared by all goroutines evaluating Monkey code, e.g. when
+// reading frozen globals concurrently, so it has to be safe for concurrent use.
+var hashKeyCache sync.Map
 
 func (obj *Boolean) HashKey() HashKey {
-	if value, ok := hashKeyCache[obj]; ok {
-		return value
+	if value, ok := hashKeyCache.Load(obj); ok {
+		return value.(HashKey)
 	}
 
 	var value uint64
@@ -87,32 +323,46 @@ func (obj *Boolean) HashKey() HashKey {
 	}
 
 	v := HashKey{obj.Type(), value}
-	hashKeyCache[obj] = v
+	hashKeyCache.Store(obj, v)
 	return v
 }
 
 func (obj *Integer) HashKey() HashKey {
-	if value, ok := hashKeyCache[obj]; ok {
-		return value
+	if value, ok := hashKeyCache.Load(obj); ok {
+		return value.(HashKey)
 	}
 	v := HashKey{obj.Type(), uint64(obj.Value)}
-	hashKeyCache[obj] = v
+	hashKeyCache.Store(obj, v)
 	return v
 }
 
 func (obj *String) HashKey() HashKey {
-	if value, ok := hashKeyCache[obj]; ok {
-		return value
+	if value, ok := hashKeyCache.Load(obj); ok {
+		return value.(HashKey)
 	}
 
 	h := fnv.New64a()
 	h.Write([]byte(obj.Value))
 
 	v := HashKey{obj.Type(), h.Sum64()}
-	hashKeyCache[obj] = v
+	hashKeyCache.Store(obj, v)
 	return v
 }
 
+// HashKey combines the hash keys of all elements. It must only be called on
+// tuples whose elements are all Hashable.
+func (obj *Tuple) HashKey() HashKey {
+	h := fnv.New64a()
+
+	for _, element := range obj.Elements {
+		key := element.(Hashable).HashKey()
+		h.Write([]byte(key.Type))
+		h.Write([]byte(fmt.Sprintf(":%d;", key.Value)))
+	}
+
+	return HashKey{obj.Type(), h.Sum64()}
+}
+
 type Integer struct {
 	Value int64
 }
@@ -127,6 +377,13 @@ type Boolean struct {
 func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJECT }
 func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
 
+// Unit is the result of code that produces no value, like let statements,
+// loops and calls to puts.
+type Unit struct{}
+
+func (u *Unit) Type() ObjectType { return UNIT_OBJECT }
+func (u *Unit) Inspect() string  { return "unit" }
+
 type ReturnValue struct {
 	Value Object
 }
@@ -138,6 +395,18 @@ type Error struct {
 	Message string
 	Line    int
 	Column  int
+
+	// Stack is the chain of calls that led to the error, innermost call
+	// first. It is empty for errors raised at the top level. Calls in tail
+	// position replace the frame of their caller.
+	Stack []StackFrame
+}
+
+// StackFrame is the call of Function at Line:Column.
+type StackFrame struct {
+	Function string
+	Line     int
+	Column   int
 }
 
 func (err *Error) Type() ObjectType { return ERROR_OBJECT }
@@ -145,10 +414,40 @@ func (err *Error) Inspect() string {
 	return fmt.Sprintf("Error at position %d:%d - %s", err.Line, err.Column, err.Message)
 }
 
+// Traceback renders the stack of the error, one call per line. Runs of the
+// same call, as produced by recursion, are collapsed into a single line.
+func (err *Error) Traceback() string {
+	var out bytes.Buffer
+
+	for i := 0; i < len(err.Stack); {
+		frame := err.Stack[i]
+
+		repeated := 1
+		for i+repeated < len(err.Stack) && err.Stack[i+repeated] == frame {
+			repeated++
+		}
+
+		fmt.Fprintf(&out, "\tin %s called at %d:%d", frame.Function, frame.Line, frame.Column)
+		if repeated > 1 {
+			fmt.Fprintf(&out, " (%d times)", repeated)
+		}
+		out.WriteString("\n")
+
+		i += repeated
+	}
+
+	return out.String()
+}
+
+// Error implements the error interface so that embedders can return Monkey
+// errors as Go errors.
+func (err *Error) Error() string { return err.Inspect() }
+
 type Function struct {
-	Parameters []*ast.Identifier
-	Body       *ast.BlockStatement
-	Env        *Environment
+	Parameters  []*ast.Identifier
+	Body        *ast.BlockStatement
+	Env         *Environment
+	IsGenerator bool
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJECT }
@@ -160,7 +459,11 @@ func (f *Function) Inspect() string {
 		params = append(params, param.String())
 	}
 
-	out.WriteString("fn(")
+	out.WriteString("fn")
+	if f.IsGenerator {
+		out.WriteString("*")
+	}
+	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
@@ -187,6 +490,11 @@ type BuiltinFunction func(args ...Object) (Object, error)
 
 type Builtin struct {
 	Fn BuiltinFunction
+
+	// FrameFn is called instead of Fn if it is set. It is passed the frame
+	// of the call, so that the functions a builtin calls back, like the
+	// function passed to map, continue its call stack.
+	FrameFn func(frame *Frame, args ...Object) (Object, error)
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJECT }
@@ -194,23 +502,11 @@ func (b *Builtin) Inspect() string  { return "builtin function" }
 
 type Array struct {
 	Elements []Object
+	Frozen   bool
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJECT }
-func (a *Array) Inspect() string {
-	var out bytes.Buffer
-
-	elements := []string{}
-	for _, el := range a.Elements {
-		elements = append(elements, el.Inspect())
-	}
-
-	out.WriteString("[")
-	out.WriteString(strings.Join(elements, ", "))
-	out.WriteString("]")
-
-	return out.String()
-}
+func (a *Array) Inspect() string  { return inspect(a, map[Object]bool{}) }
 
 type HashPair struct {
 	Key   Object
@@ -218,40 +514,202 @@ type HashPair struct {
 }
 
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	Pairs  map[HashKey]HashPair
+	Keys   []HashKey // in insertion order
+	Frozen bool
 }
 
-func (h *Hash) Type() ObjectType { return HASH_OBJECT }
-func (h *Hash) Inspect() string {
-	var out bytes.Buffer
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
 
-	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+// Set stores pair under key. New keys are appended to the iteration order,
+// existing keys keep their position.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Keys = append(h.Keys, key)
 	}
 
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
+	h.Pairs[key] = pair
+}
 
-	return out.String()
+// Ordered returns the pairs of the hash in insertion order.
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Keys))
+	for _, key := range h.Keys {
+		pairs = append(pairs, h.Pairs[key])
+	}
+
+	return pairs
 }
 
+func (h *Hash) Type() ObjectType { return HASH_OBJECT }
+func (h *Hash) Inspect() string  { return inspect(h, map[Object]bool{}) }
+
 type Maybe struct {
 	Value Object
 }
 
 func (m *Maybe) Type() ObjectType { return MAYBE_OBJECT }
 func (m *Maybe) Inspect() string {
+	if m.Value == nil {
+		return "maybe([no value])"
+	}
+
+	return inspect(m, map[Object]bool{})
+}
+
+// Tuple is an immutable, fixed size sequence of values.
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Type() ObjectType { return TUPLE_OBJECT }
+func (t *Tuple) Inspect() string  { return inspect(t, map[Object]bool{}) }
+
+// Set is an unordered collection of distinct Hashable values.
+type Set struct {
+	Elements map[HashKey]Object
+}
+
+func NewSet() *Set {
+	return &Set{Elements: make(map[HashKey]Object)}
+}
+
+// Add inserts obj into the set. obj must be Hashable.
+func (s *Set) Add(obj Object) {
+	s.Elements[obj.(Hashable).HashKey()] = obj
+}
+
+func (s *Set) Contains(obj Hashable) bool {
+	_, ok := s.Elements[obj.HashKey()]
+	return ok
+}
+
+// Sorted returns the elements of the set in a deterministic order: grouped
+// by type and ordered by value within each type.
+func (s *Set) Sorted() []Object {
+	elements := make([]Object, 0, len(s.Elements))
+	for _, element := range s.Elements {
+		elements = append(elements, element)
+	}
+
+	sort.Slice(elements, func(i, j int) bool {
+		return lessForSorting(elements[i], elements[j])
+	})
+
+	return elements
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJECT }
+func (s *Set) Inspect() string {
+	if len(s.Elements) == 0 {
+		return "set()"
+	}
+
 	var out bytes.Buffer
 
-	out.WriteString("maybe(")
-	if m.Value == nil {
-		out.WriteString("[no value]")
-	} else {
-		out.WriteString(m.Value.Inspect())
+	elements := []string{}
+	for _, el := range s.Sorted() {
+		elements = append(elements, el.Inspect())
 	}
-	out.WriteString(")")
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
 
 	return out.String()
 }
+
+func lessForSorting(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+
+	if result, ok := Compare(a, b); ok {
+		return result < 0
+	}
+
+	return a.Inspect() < b.Inspect()
+}
+
+// Range is a lazy sequence of the integers from Start up to, but excluding,
+// Stop.
+type Range struct {
+	Start int64
+	Stop  int64
+}
+
+func (r *Range) Len() int {
+	if r.Stop <= r.Start {
+		return 0
+	}
+
+	return int(r.Stop - r.Start)
+}
+
+// Get returns the integer at index i, which must be in [0, Len()).
+func (r *Range) Get(i int) *Integer { return &Integer{Value: r.Start + int64(i)} }
+
+func (r *Range) Contains(value int64) bool { return r.Start <= value && value < r.Stop }
+
+// Elements materializes the range.
+func (r *Range) Elements() []Object {
+	elements := make([]Object, 0, r.Len())
+	for i := 0; i < r.Len(); i++ {
+		elements = append(elements, r.Get(i))
+	}
+
+	return elements
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJECT }
+func (r *Range) Inspect() string  { return fmt.Sprintf("%d..<%d", r.Start, r.Stop) }
+
+// Freeze makes obj and every value reachable from it immutable. Tuples, sets,
+// vectors, dicts and scalars are immutable already, but may contain arrays and
+// hashes. Functions reach the values bound in the environments they capture,
+// which is every value visible where the function was defined. The only
+// builtin that changes a value in place, push, rejects frozen arrays.
+//
+// Builtins, like composed functions, hide the values they hold and are not
+// frozen. Sequences keep advancing when they are iterated.
+func Freeze(obj Object) {
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, element := range obj.Elements {
+			Freeze(element)
+		}
+	case *Hash:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, pair := range obj.Pairs {
+			Freeze(pair.Key)
+			Freeze(pair.Value)
+		}
+	case *Tuple:
+		for _, element := range obj.Elements {
+			Freeze(element)
+		}
+	case *Vector:
+		for _, element := range obj.Elements() {
+			Freeze(element)
+		}
+	case *Dict:
+		for _, pair := range obj.Pairs() {
+			Freeze(pair.Value)
+		}
+	case *Maybe:
+		Freeze(obj.Value)
+	case *Function:
+		for env := obj.Env; env != nil; env = env.outer {
+			env.freezeValues()
+		}
+	}
+}
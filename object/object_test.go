@@ -20,3 +20,34 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestTupleHashKey(t *testing.T) {
+	tuple1 := &Tuple{Elements: []Object{&Integer{Value: 1}, &String{Value: "one"}}}
+	tuple2 := &Tuple{Elements: []Object{&Integer{Value: 1}, &String{Value: "one"}}}
+	swapped := &Tuple{Elements: []Object{&String{Value: "one"}, &Integer{Value: 1}}}
+
+	if tuple1.HashKey() != tuple2.HashKey() {
+		t.Errorf("tuples with same content have different hash keys")
+	}
+	if tuple1.HashKey() == swapped.HashKey() {
+		t.Errorf("tuples with different content have same hash keys")
+	}
+}
+
+func TestSetInspectOrdering(t *testing.T) {
+	set := NewSet()
+	set.Add(&String{Value: "b"})
+	set.Add(&Integer{Value: 10})
+	set.Add(&String{Value: "a"})
+	set.Add(&Integer{Value: 2})
+	set.Add(&Boolean{Value: true})
+
+	expected := `{true, 2, 10, "a", "b"}`
+	if set.Inspect() != expected {
+		t.Errorf("set.Inspect() wrong. want=%q, got=%q", expected, set.Inspect())
+	}
+
+	if NewSet().Inspect() != "set()" {
+		t.Errorf("empty set.Inspect() wrong. got=%q", NewSet().Inspect())
+	}
+}
@@ -0,0 +1,365 @@
+package object
+
+import (
+	"hash/fnv"
+	"math/bits"
+)
+
+const (
+	vectorBits  = 5
+	vectorWidth = 1 << vectorBits
+	vectorMask  = vectorWidth - 1
+)
+
+// Vector is a persistent array. It is a 32-way trie with a tail buffer like
+// Clojure's PersistentVector: every update returns a new vector that shares
+// all untouched nodes with the original, so appending and updating take
+// O(log32 n) time and leave the original unchanged.
+type Vector struct {
+	count  int // number of elements in the trie and tail, including dropped ones
+	offset int // number of elements dropped from the front by Rest
+	shift  uint
+	root   *vectorNode
+	tail   []Object
+}
+
+type vectorNode struct {
+	children [vectorWidth]any // *vectorNode for branches, Object for leaves
+}
+
+func NewVector(elements ...Object) *Vector {
+	vector := &Vector{shift: vectorBits, root: &vectorNode{}}
+	for _, element := range elements {
+		vector = vector.Append(element)
+	}
+
+	return vector
+}
+
+func (v *Vector) Len() int { return v.count - v.offset }
+
+// Get returns the element at index i, which must be in [0, Len()).
+func (v *Vector) Get(i int) Object {
+	i += v.offset
+	if i >= v.tailOffset() {
+		return v.tail[i-v.tailOffset()]
+	}
+
+	node := v.root
+	for level := v.shift; level > 0; level -= vectorBits {
+		node = node.children[(i>>level)&vectorMask].(*vectorNode)
+	}
+
+	return node.children[i&vectorMask].(Object)
+}
+
+// Elements returns all elements of the vector as a new slice.
+func (v *Vector) Elements() []Object {
+	elements := make([]Object, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elements = append(elements, v.Get(i))
+	}
+
+	return elements
+}
+
+// Append returns a new vector with value added to the end.
+func (v *Vector) Append(value Object) *Vector {
+	result := *v
+
+	if v.count-v.tailOffset() < vectorWidth {
+		result.tail = append(append(make([]Object, 0, len(v.tail)+1), v.tail...), value)
+		result.count++
+		return &result
+	}
+
+	// The tail is full: push it into the trie and start a new one.
+	tailNode := &vectorNode{}
+	for i, element := range v.tail {
+		tailNode.children[i] = element
+	}
+
+	if (v.count >> vectorBits) > (1 << v.shift) {
+		// The trie is full as well and grows by one level.
+		root := &vectorNode{}
+		root.children[0] = v.root
+		root.children[1] = newVectorPath(v.shift, tailNode)
+		result.root = root
+		result.shift = v.shift + vectorBits
+	} else {
+		result.root = v.pushTail(v.shift, v.root, tailNode)
+	}
+
+	result.tail = []Object{value}
+	result.count++
+	return &result
+}
+
+// Set returns a new vector with the element at index i, which must be in
+// [0, Len()), replaced by value.
+func (v *Vector) Set(i int, value Object) *Vector {
+	result := *v
+	i += v.offset
+
+	if i >= v.tailOffset() {
+		result.tail = append([]Object{}, v.tail...)
+		result.tail[i-v.tailOffset()] = value
+		return &result
+	}
+
+	result.root = setInVectorNode(v.root, v.shift, i, value)
+	return &result
+}
+
+// Rest returns a new vector without the first element in constant time.
+func (v *Vector) Rest() *Vector {
+	result := *v
+	if result.Len() > 0 {
+		result.offset++
+	}
+
+	return &result
+}
+
+func (v *Vector) tailOffset() int {
+	if v.count < vectorWidth {
+		return 0
+	}
+
+	return ((v.count - 1) >> vectorBits) << vectorBits
+}
+
+func (v *Vector) pushTail(level uint, parent *vectorNode, tailNode *vectorNode) *vectorNode {
+	node := *parent
+	index := ((v.count - 1) >> level) & vectorMask
+
+	if level == vectorBits {
+		node.children[index] = tailNode
+		return &node
+	}
+
+	if child, ok := parent.children[index].(*vectorNode); ok {
+		node.children[index] = v.pushTail(level-vectorBits, child, tailNode)
+	} else {
+		node.children[index] = newVectorPath(level-vectorBits, tailNode)
+	}
+
+	return &node
+}
+
+func newVectorPath(level uint, node *vectorNode) *vectorNode {
+	if level == 0 {
+		return node
+	}
+
+	path := &vectorNode{}
+	path.children[0] = newVectorPath(level-vectorBits, node)
+	return path
+}
+
+func setInVectorNode(parent *vectorNode, level uint, i int, value Object) *vectorNode {
+	node := *parent
+
+	if level == 0 {
+		node.children[i&vectorMask] = value
+		return &node
+	}
+
+	index := (i >> level) & vectorMask
+	node.children[index] = setInVectorNode(parent.children[index].(*vectorNode), level-vectorBits, i, value)
+	return &node
+}
+
+func (v *Vector) Type() ObjectType { return VECTOR_OBJECT }
+func (v *Vector) Inspect() string  { return inspect(v, map[Object]bool{}) }
+
+const dictBits = 5
+
+// Dict is a persistent hash. It is a hash array mapped trie: every update
+// returns a new dict that shares all untouched nodes with the original, so
+// lookups and updates take O(log32 n) time and leave the original unchanged.
+type Dict struct {
+	count int
+	root  *dictNode
+}
+
+// dictNode is a trie node. Only the children whose bit is set in bitmap are
+// stored. Nodes below the last level hold colliding entries instead.
+type dictNode struct {
+	bitmap     uint32
+	children   []dictEntry
+	collisions []HashPair
+}
+
+type dictEntry struct {
+	key  HashKey
+	pair HashPair
+	node *dictNode // set for branches, nil for leaves
+}
+
+func NewDict() *Dict {
+	return &Dict{root: &dictNode{}}
+}
+
+func (d *Dict) Len() int { return d.count }
+
+// Get returns the pair stored under key.
+func (d *Dict) Get(key HashKey) (HashPair, bool) {
+	hash := dictHash(key)
+	node := d.root
+
+	for shift := uint(0); ; shift += dictBits {
+		if shift >= 64 {
+			for _, pair := range node.collisions {
+				if pair.Key.(Hashable).HashKey() == key {
+					return pair, true
+				}
+			}
+			return HashPair{}, false
+		}
+
+		bit := uint32(1) << ((hash >> shift) & 31)
+		if node.bitmap&bit == 0 {
+			return HashPair{}, false
+		}
+
+		entry := node.children[bits.OnesCount32(node.bitmap&(bit-1))]
+		if entry.node == nil {
+			return entry.pair, entry.key == key
+		}
+
+		node = entry.node
+	}
+}
+
+// Set returns a new dict with key mapped to pair.
+func (d *Dict) Set(key HashKey, pair HashPair) *Dict {
+	root, added := d.root.set(0, dictHash(key), key, pair)
+
+	count := d.count
+	if added {
+		count++
+	}
+
+	return &Dict{count: count, root: root}
+}
+
+// Delete returns a new dict without key.
+func (d *Dict) Delete(key HashKey) *Dict {
+	root, removed := d.root.delete(0, dictHash(key), key)
+	if !removed {
+		return d
+	}
+
+	return &Dict{count: d.count - 1, root: root}
+}
+
+// Pairs returns all pairs in trie order, which only depends on the keys.
+func (d *Dict) Pairs() []HashPair {
+	pairs := make([]HashPair, 0, d.count)
+	d.root.collect(&pairs)
+	return pairs
+}
+
+func (node *dictNode) set(shift uint, hash uint64, key HashKey, pair HashPair) (*dictNode, bool) {
+	if shift >= 64 {
+		result := &dictNode{collisions: append([]HashPair{}, node.collisions...)}
+		for i, existing := range result.collisions {
+			if existing.Key.(Hashable).HashKey() == key {
+				result.collisions[i] = pair
+				return result, false
+			}
+		}
+		result.collisions = append(result.collisions, pair)
+		return result, true
+	}
+
+	bit := uint32(1) << ((hash >> shift) & 31)
+	index := bits.OnesCount32(node.bitmap & (bit - 1))
+	result := &dictNode{bitmap: node.bitmap, children: append([]dictEntry{}, node.children...)}
+
+	if node.bitmap&bit == 0 {
+		result.bitmap |= bit
+		result.children = append(result.children[:index], append([]dictEntry{{key: key, pair: pair}}, result.children[index:]...)...)
+		return result, true
+	}
+
+	entry := node.children[index]
+	switch {
+	case entry.node != nil:
+		child, added := entry.node.set(shift+dictBits, hash, key, pair)
+		result.children[index] = dictEntry{node: child}
+		return result, added
+	case entry.key == key:
+		result.children[index] = dictEntry{key: key, pair: pair}
+		return result, false
+	default:
+		// Two different keys share this slot: push both one level down.
+		child, _ := (&dictNode{}).set(shift+dictBits, dictHash(entry.key), entry.key, entry.pair)
+		child, _ = child.set(shift+dictBits, hash, key, pair)
+		result.children[index] = dictEntry{node: child}
+		return result, true
+	}
+}
+
+func (node *dictNode) delete(shift uint, hash uint64, key HashKey) (*dictNode, bool) {
+	if shift >= 64 {
+		for i, existing := range node.collisions {
+			if existing.Key.(Hashable).HashKey() == key {
+				collisions := append(append([]HashPair{}, node.collisions[:i]...), node.collisions[i+1:]...)
+				return &dictNode{collisions: collisions}, true
+			}
+		}
+		return node, false
+	}
+
+	bit := uint32(1) << ((hash >> shift) & 31)
+	if node.bitmap&bit == 0 {
+		return node, false
+	}
+
+	index := bits.OnesCount32(node.bitmap & (bit - 1))
+	entry := node.children[index]
+	result := &dictNode{bitmap: node.bitmap, children: append([]dictEntry{}, node.children...)}
+
+	if entry.node == nil {
+		if entry.key != key {
+			return node, false
+		}
+
+		result.bitmap &^= bit
+		result.children = append(result.children[:index], result.children[index+1:]...)
+		return result, true
+	}
+
+	child, removed := entry.node.delete(shift+dictBits, hash, key)
+	if !removed {
+		return node, false
+	}
+
+	result.children[index] = dictEntry{node: child}
+	return result, true
+}
+
+func (node *dictNode) collect(pairs *[]HashPair) {
+	*pairs = append(*pairs, node.collisions...)
+
+	for _, entry := range node.children {
+		if entry.node != nil {
+			entry.node.collect(pairs)
+		} else {
+			*pairs = append(*pairs, entry.pair)
+		}
+	}
+}
+
+// dictHash mixes the type of a hash key into its value, so that e.g. the
+// integer 1 and true do not always end up in the same slot.
+func dictHash(key HashKey) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key.Type))
+	return h.Sum64() ^ key.Value
+}
+
+func (d *Dict) Type() ObjectType { return DICT_OBJECT }
+func (d *Dict) Inspect() string  { return inspect(d, map[Object]bool{}) }
@@ -0,0 +1,159 @@
+package object
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"testing"
+)
+
+func TestVector(t *testing.T) {
+	reference := []Object{}
+	vector := NewVector()
+	versions := []*Vector{}
+
+	for i := 0; i < 5000; i++ {
+		versions = append(versions, vector)
+		reference = append(reference, &Integer{Value: int64(i)})
+		vector = vector.Append(&Integer{Value: int64(i)})
+	}
+
+	if vector.Len() != len(reference) {
+		t.Fatalf("vector has wrong length. want=%d, got=%d", len(reference), vector.Len())
+	}
+
+	for i, expected := range reference {
+		if !Equal(vector.Get(i), expected) {
+			t.Fatalf("vector.Get(%d) wrong. want=%s, got=%s", i, expected.Inspect(), vector.Get(i).Inspect())
+		}
+	}
+
+	for i, version := range versions {
+		if version.Len() != i {
+			t.Fatalf("old version was modified. want length=%d, got=%d", i, version.Len())
+		}
+	}
+
+	random := rand.New(rand.NewSource(1))
+	updated := vector
+	for n := 0; n < 1000; n++ {
+		i := random.Intn(len(reference))
+		reference[i] = &String{Value: "updated"}
+		updated = updated.Set(i, reference[i])
+	}
+
+	for i, expected := range reference {
+		if !Equal(updated.Get(i), expected) {
+			t.Fatalf("updated.Get(%d) wrong. want=%s, got=%s", i, expected.Inspect(), updated.Get(i).Inspect())
+		}
+		if !Equal(vector.Get(i), &Integer{Value: int64(i)}) {
+			t.Fatalf("Set modified the original vector at %d", i)
+		}
+	}
+
+	rest := vector
+	for i := 0; i < 100; i++ {
+		rest = rest.Rest()
+	}
+
+	if rest.Len() != vector.Len()-100 || !Equal(rest.Get(0), &Integer{Value: 100}) {
+		t.Errorf("Rest wrong. got length=%d, first=%s", rest.Len(), rest.Get(0).Inspect())
+	}
+
+	rest = rest.Append(&Integer{Value: -1}).Set(0, &Integer{Value: -2})
+	if !Equal(rest.Get(rest.Len()-1), &Integer{Value: -1}) || !Equal(rest.Get(0), &Integer{Value: -2}) {
+		t.Errorf("Append or Set after Rest wrong. got %s", rest.Inspect())
+	}
+}
+
+func TestDict(t *testing.T) {
+	reference := map[HashKey]HashPair{}
+	dict := NewDict()
+	random := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 5000; n++ {
+		key := &Integer{Value: int64(random.Intn(2000))}
+
+		if random.Intn(4) == 0 {
+			delete(reference, key.HashKey())
+			dict = dict.Delete(key.HashKey())
+		} else {
+			pair := HashPair{Key: key, Value: &Integer{Value: int64(n)}}
+			reference[key.HashKey()] = pair
+			dict = dict.Set(key.HashKey(), pair)
+		}
+	}
+
+	if dict.Len() != len(reference) {
+		t.Fatalf("dict has wrong length. want=%d, got=%d", len(reference), dict.Len())
+	}
+
+	for key, expected := range reference {
+		pair, ok := dict.Get(key)
+		if !ok || !Equal(pair.Value, expected.Value) {
+			t.Fatalf("dict.Get(%v) wrong. want=%s", key, expected.Value.Inspect())
+		}
+	}
+
+	if len(dict.Pairs()) != len(reference) {
+		t.Errorf("dict.Pairs() has wrong length. want=%d, got=%d", len(reference), len(dict.Pairs()))
+	}
+
+	added := &Integer{Value: 5000}
+	updated := dict.Set(added.HashKey(), HashPair{Key: added, Value: added})
+	if _, ok := dict.Get(added.HashKey()); ok || dict.Len() != len(reference) {
+		t.Errorf("Set modified the original dict")
+	}
+
+	removed := updated.Delete(added.HashKey())
+	if _, ok := updated.Get(added.HashKey()); !ok || removed.Len() != dict.Len() {
+		t.Errorf("Delete modified the original dict")
+	}
+}
+
+func TestDictCollisions(t *testing.T) {
+	typeHash := func(objectType ObjectType) uint64 {
+		h := fnv.New64a()
+		h.Write([]byte(objectType))
+		return h.Sum64()
+	}
+
+	// Both keys end up with the same trie hash, but are different keys.
+	integer := &Integer{Value: 42}
+	boolean := &Boolean{Value: true}
+	collidingValue := typeHash(INTEGER_OBJECT) ^ typeHash(BOOLEAN_OBJECT) ^ 42
+	collidingKey := HashKey{Type: BOOLEAN_OBJECT, Value: collidingValue}
+
+	dict := NewDict().
+		Set(integer.HashKey(), HashPair{Key: integer, Value: &String{Value: "integer"}}).
+		Set(boolean.HashKey(), HashPair{Key: boolean, Value: &String{Value: "boolean"}})
+
+	if dictHash(integer.HashKey()) != dictHash(collidingKey) {
+		t.Fatalf("keys do not collide")
+	}
+
+	colliding := dict.Set(collidingKey, HashPair{Key: &collisionKey{collidingKey}, Value: &String{Value: "colliding"}})
+	if colliding.Len() != 3 {
+		t.Fatalf("dict has wrong length. want=%d, got=%d", 3, colliding.Len())
+	}
+
+	for key, expected := range map[HashKey]string{integer.HashKey(): "integer", boolean.HashKey(): "boolean", collidingKey: "colliding"} {
+		pair, ok := colliding.Get(key)
+		if !ok || pair.Value.(*String).Value != expected {
+			t.Errorf("colliding.Get(%v) wrong. want=%s", key, expected)
+		}
+	}
+
+	removed := colliding.Delete(integer.HashKey())
+	if _, ok := removed.Get(integer.HashKey()); ok || removed.Len() != 2 {
+		t.Errorf("Delete of colliding key failed")
+	}
+	if pair, ok := removed.Get(collidingKey); !ok || pair.Value.(*String).Value != "colliding" {
+		t.Errorf("Delete removed wrong colliding key")
+	}
+}
+
+type collisionKey struct{ key HashKey }
+
+func (c *collisionKey) Type() ObjectType { return BOOLEAN_OBJECT }
+func (c *collisionKey) Inspect() string  { return "collision" }
+func (c *collisionKey) HashKey() HashKey { return c.key }
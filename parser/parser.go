@@ -3,16 +3,23 @@ package parser
 import (
 	"fmt"
 	"github.com/hendrikbursian/monkey-programming-language/ast"
+	"github.com/hendrikbursian/monkey-programming-language/diagnostic"
 	"github.com/hendrikbursian/monkey-programming-language/lexer"
 	"github.com/hendrikbursian/monkey-programming-language/token"
+	"sort"
 	"strconv"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	PIPE
+	COMPOSE
 	EQUALS
 	LESSGREATER
+	UNION
+	INTERSECTION
+	RANGE
 	SUM
 	PRODUCT
 	PREFIX
@@ -22,10 +29,18 @@ const (
 )
 
 var precedences = map[token.TokenType]int{
+	token.PIPE:                PIPE,
+	token.COMPOSE_RIGHT:       COMPOSE,
+	token.COMPOSE_LEFT:        COMPOSE,
 	token.EQUAL:               EQUALS,
 	token.NOT_EQUAL:           EQUALS,
 	token.LESS_THAN:           LESSGREATER,
 	token.GREATER_THAN:        LESSGREATER,
+	token.IN:                  LESSGREATER,
+	token.BAR:                 UNION,
+	token.AMPERSAND:           INTERSECTION,
+	token.RANGE:               RANGE,
+	token.RANGE_EXCLUSIVE:     RANGE,
 	token.PLUS:                SUM,
 	token.MINUS:               SUM,
 	token.SLASH:               PRODUCT,
@@ -45,7 +60,21 @@ type Parser struct {
 
 	currentToken token.Token
 	peekToken    token.Token
-	errors       []string
+	diagnostics  []*diagnostic.Diagnostic
+
+	// panicking is set after an error until the parser has skipped to the
+	// next statement. Errors in between are follow-up errors and dropped.
+	panicking bool
+
+	// depth is the number of braces opened and not yet closed before the
+	// current token.
+	depth int
+
+	// inGenerator is set while parsing the body of a `fn*` literal.
+	inGenerator bool
+
+	// inFunction is set while parsing the body of any function literal.
+	inFunction bool
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -53,8 +82,8 @@ type Parser struct {
 
 func New(lexer *lexer.Lexer) *Parser {
 	parser := &Parser{
-		lexer:  lexer,
-		errors: []string{},
+		lexer:       lexer,
+		diagnostics: []*diagnostic.Diagnostic{},
 	}
 
 	parser.prefixParseFns = make(map[token.TokenType]prefixParseFn)
@@ -80,6 +109,14 @@ func New(lexer *lexer.Lexer) *Parser {
 	parser.registerInfix(token.NOT_EQUAL, parser.parseInfixExpression)
 	parser.registerInfix(token.LESS_THAN, parser.parseInfixExpression)
 	parser.registerInfix(token.GREATER_THAN, parser.parseInfixExpression)
+	parser.registerInfix(token.IN, parser.parseInfixExpression)
+	parser.registerInfix(token.BAR, parser.parseInfixExpression)
+	parser.registerInfix(token.AMPERSAND, parser.parseInfixExpression)
+	parser.registerInfix(token.RANGE, parser.parseInfixExpression)
+	parser.registerInfix(token.RANGE_EXCLUSIVE, parser.parseInfixExpression)
+	parser.registerInfix(token.PIPE, parser.parseInfixExpression)
+	parser.registerInfix(token.COMPOSE_RIGHT, parser.parseInfixExpression)
+	parser.registerInfix(token.COMPOSE_LEFT, parser.parseInfixExpression)
 	parser.registerInfix(token.LEFT_PAREN, parser.parseCallExpression)
 	parser.registerInfix(token.LEFT_SQUARE_BRACKET, parser.parseIndexExpression)
 	parser.registerInfix(token.DOT, parser.parsePropertyExpression)
@@ -90,17 +127,82 @@ func New(lexer *lexer.Lexer) *Parser {
 	return parser
 }
 
+// Diagnostics returns the problems found by the lexer and the parser, ordered
+// by position.
+func (parser *Parser) Diagnostics() []*diagnostic.Diagnostic {
+	diagnostics := append(append([]*diagnostic.Diagnostic{}, parser.lexer.Diagnostics()...), parser.diagnostics...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Start, diagnostics[j].Start
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+
+	return diagnostics
+}
+
+// Errors returns the diagnostics of severity error formatted as
+// "message at line:column".
 func (parser *Parser) Errors() []string {
-	return parser.errors
+	errors := []string{}
+	for _, d := range parser.Diagnostics() {
+		if d.Severity == diagnostic.ERROR {
+			errors = append(errors, d.String())
+		}
+	}
+
+	return errors
 }
 
-func (parser *Parser) peekError(tokenType token.TokenType) {
-	message := fmt.Sprintf("In line %d column %d expected next token to be '%s' got '%s' instead.", parser.peekToken.Line, parser.peekToken.Column, tokenType, parser.peekToken.Type)
-	parser.errors = append(parser.errors, message)
+func (parser *Parser) addError(code diagnostic.Code, tok token.Token, format string, a ...interface{}) *diagnostic.Diagnostic {
+	d := diagnostic.AtToken(diagnostic.ERROR, code, tok, format, a...)
+	if !parser.panicking {
+		parser.diagnostics = append(parser.diagnostics, d)
+	}
+
+	parser.panicking = true
+	return d
+}
+
+// synchronize skips the rest of a statement that contains an error. It stops
+// at the end of the statement, before a token that starts a new statement or
+// before the brace that closes the current block. statementDepth is the depth
+// at the start of the statement, so braces opened by the statement before the
+// error are skipped up to their closing brace.
+func (parser *Parser) synchronize(statementDepth int) {
+	for !parser.peekTokenIs(token.EOF) {
+		depth := parser.depth - statementDepth
+		if parser.currentTokenIs(token.LEFT_CURLY_BRACE) {
+			depth++
+		} else if parser.currentTokenIs(token.RIGHT_CURLY_BRACE) {
+			depth--
+		}
+
+		if depth <= 0 {
+			if parser.currentTokenIs(token.SEMICOLON) || parser.peekTokenIs(token.RIGHT_CURLY_BRACE) {
+				break
+			}
 
+			if parser.peekTokenIs(token.LET) || parser.peekTokenIs(token.CONST) || parser.peekTokenIs(token.RETURN) {
+				break
+			}
+		}
+
+		parser.nextToken()
+	}
+
+	parser.panicking = false
+}
+
+func (parser *Parser) peekError(tokenType token.TokenType) {
+	parser.addError(diagnostic.UNEXPECTED_TOKEN, parser.peekToken, "expected next token to be '%s', got '%s' instead", tokenType, parser.peekToken.Type)
 }
 
 func (parser *Parser) nextToken() {
+	if parser.currentTokenIs(token.LEFT_CURLY_BRACE) {
+		parser.depth++
+	} else if parser.currentTokenIs(token.RIGHT_CURLY_BRACE) {
+		parser.depth--
+	}
+
 	parser.currentToken = parser.peekToken
 	parser.peekToken = parser.lexer.NextToken()
 }
@@ -121,8 +223,6 @@ func (parser *Parser) ParseProgram() *ast.Program {
 		statement := parser.parseStatement()
 		if statement != nil {
 			program.Statements = append(program.Statements, statement)
-		} else {
-			fmt.Printf("Parser error: Token '%s' cannot be parsed\n", parser.currentToken.Type)
 		}
 		parser.nextToken()
 	}
@@ -130,15 +230,54 @@ func (parser *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// parseStatement parses the statement at the current token. If it contains an
+// error, the parser skips to the next statement and the statement is returned
+// as far as it could be parsed, or as *ast.BadStatement.
 func (parser *Parser) parseStatement() ast.Statement {
+	start := parser.currentToken
+	depth := parser.depth
+
+	var statement ast.Statement
 	switch parser.currentToken.Type {
-	case token.LET:
-		return parser.parseLetStatement()
+	case token.LET, token.CONST:
+		if s := parser.parseLetStatement(); s != nil {
+			statement = s
+		}
 	case token.RETURN:
-		return parser.parseReturnStatement()
+		if s := parser.parseReturnStatement(); s != nil {
+			statement = s
+		}
+	case token.FOR:
+		if s := parser.parseForStatement(); s != nil {
+			statement = s
+		}
+	case token.YIELD:
+		if s := parser.parseYieldStatement(); s != nil {
+			statement = s
+		}
+	case token.DEFER:
+		if s := parser.parseDeferStatement(); s != nil {
+			statement = s
+		}
 	default:
-		return parser.parseExpressionStatement()
+		if s := parser.parseExpressionStatement(); s != nil {
+			statement = s
+		}
+	}
+
+	if statement == nil {
+		statement = &ast.BadStatement{Token: start}
+	}
+
+	if parser.panicking {
+		parser.synchronize(depth)
+	}
+
+	if bad, ok := statement.(*ast.BadStatement); ok {
+		bad.Last = parser.currentToken
 	}
+
+	return statement
 }
 
 func (parser *Parser) parseLetStatement() *ast.LetStatement {
@@ -146,16 +285,106 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 		Token: parser.currentToken,
 	}
 
-	if !parser.expectPeek(token.IDENTIFIER) {
+	if parser.peekTokenIs(token.LEFT_PAREN) {
+		parser.nextToken()
+
+		statement.Names = parser.parseFunctionParameters()
+		if statement.Names == nil {
+			return nil
+		}
+	} else {
+		if !parser.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+
+		statement.Identifier = &ast.Identifier{
+			Token: parser.currentToken,
+			Value: parser.currentToken.Literal,
+		}
+	}
+
+	if !parser.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
-	statement.Identifier = &ast.Identifier{
+	parser.nextToken()
+
+	statement.Value = parser.parseExpression(LOWEST)
+
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseForStatement() *ast.ForStatement {
+	statement := &ast.ForStatement{
 		Token: parser.currentToken,
-		Value: parser.currentToken.Literal,
 	}
 
-	if !parser.expectPeek(token.ASSIGN) {
+	var ok bool
+	statement.Identifier, statement.Names, ok = parser.parseLoopTargets()
+	if !ok {
+		return nil
+	}
+
+	parser.nextToken()
+	statement.Iterable = parser.parseExpression(LOWEST)
+
+	if !parser.expectPeek(token.LEFT_CURLY_BRACE) {
+		return nil
+	}
+
+	statement.Body = parser.parseBlockStatement()
+
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+// parseLoopTargets parses the `x in` or `(k, v) in` part of a for statement or
+// comprehension clause. It returns either the identifier or the names of a
+// destructuring loop.
+func (parser *Parser) parseLoopTargets() (*ast.Identifier, []*ast.Identifier, bool) {
+	var identifier *ast.Identifier
+	var names []*ast.Identifier
+
+	if parser.peekTokenIs(token.LEFT_PAREN) {
+		parser.nextToken()
+
+		names = parser.parseFunctionParameters()
+		if names == nil {
+			return nil, nil, false
+		}
+	} else {
+		if !parser.expectPeek(token.IDENTIFIER) {
+			return nil, nil, false
+		}
+
+		identifier = &ast.Identifier{
+			Token: parser.currentToken,
+			Value: parser.currentToken.Literal,
+		}
+	}
+
+	if !parser.expectPeek(token.IN) {
+		return nil, nil, false
+	}
+
+	return identifier, names, true
+}
+
+func (parser *Parser) parseYieldStatement() *ast.YieldStatement {
+	statement := &ast.YieldStatement{
+		Token: parser.currentToken,
+	}
+
+	if !parser.inGenerator {
+		parser.addError(diagnostic.YIELD_OUTSIDE_GENERATOR, statement.Token, "yield outside of generator function").
+			WithHint("declare the function with fn* to make it a generator")
 		return nil
 	}
 
@@ -170,6 +399,28 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 	return statement
 }
 
+func (parser *Parser) parseDeferStatement() *ast.DeferStatement {
+	statement := &ast.DeferStatement{
+		Token: parser.currentToken,
+	}
+
+	if !parser.inFunction {
+		parser.addError(diagnostic.DEFER_OUTSIDE_FUNCTION, statement.Token, "defer outside of function").
+			WithHint("move the defer statement into the body of a function")
+		return nil
+	}
+
+	parser.nextToken()
+
+	statement.Expression = parser.parseExpression(LOWEST)
+
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
 func (parser *Parser) parseReturnStatement() *ast.ReturnStatement {
 	statement := &ast.ReturnStatement{
 		Token: parser.currentToken,
@@ -204,13 +455,18 @@ func (parser *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (parser *Parser) parseExpression(precedence int) ast.Expression {
 	defer untrace(trace("parseExpression"))
 
+	start := parser.currentToken
+
 	prefix := parser.prefixParseFns[parser.currentToken.Type]
 	if prefix == nil {
 		parser.noPrefixParseFnError(parser.currentToken)
-		return nil
+		return &ast.BadExpression{Token: start}
 	}
 
 	leftExpression := prefix()
+	if leftExpression == nil {
+		return &ast.BadExpression{Token: start}
+	}
 
 	for !parser.peekTokenIs(token.SEMICOLON) &&
 		!parser.peekTokenIs(token.LEFT_CURLY_BRACE) &&
@@ -225,21 +481,117 @@ func (parser *Parser) parseExpression(precedence int) ast.Expression {
 		parser.nextToken()
 
 		leftExpression = infix(leftExpression)
+		if leftExpression == nil {
+			return &ast.BadExpression{Token: start}
+		}
 	}
 
 	return leftExpression
 }
 
 func (parser *Parser) parseGroupedExpression() ast.Expression {
+	tuple := &ast.TupleLiteral{
+		Token:    parser.currentToken,
+		Elements: []ast.Expression{},
+	}
+
+	if parser.peekTokenIs(token.RIGHT_PAREN) {
+		parser.nextToken()
+		tuple.EndToken = parser.currentToken
+
+		if parser.peekTokenIs(token.ARROW) {
+			return parser.parseArrowFunction(tuple.Token, []*ast.Identifier{})
+		}
+
+		return tuple
+	}
+
 	parser.nextToken()
 
 	expression := parser.parseExpression(LOWEST)
 
+	if !parser.peekTokenIs(token.COMMA) {
+		if !parser.expectPeek(token.RIGHT_PAREN) {
+			return nil
+		}
+
+		if parser.peekTokenIs(token.ARROW) {
+			return parser.parseArrowParameters(tuple.Token, []ast.Expression{expression})
+		}
+
+		return expression
+	}
+
+	// A comma turns the grouped expression into a tuple: `(a, b)` or `(a,)`.
+	tuple.Elements = append(tuple.Elements, expression)
+
+	for parser.peekTokenIs(token.COMMA) {
+		parser.nextToken()
+
+		if parser.peekTokenIs(token.RIGHT_PAREN) {
+			break
+		}
+
+		parser.nextToken()
+		tuple.Elements = append(tuple.Elements, parser.parseExpression(LOWEST))
+	}
+
 	if !parser.expectPeek(token.RIGHT_PAREN) {
 		return nil
 	}
+	tuple.EndToken = parser.currentToken
 
-	return expression
+	if parser.peekTokenIs(token.ARROW) {
+		return parser.parseArrowParameters(tuple.Token, tuple.Elements)
+	}
+
+	return tuple
+}
+
+// parseArrowParameters checks that the elements of `(a, b)` before an arrow
+// are identifiers and parses the arrow function.
+func (parser *Parser) parseArrowParameters(tok token.Token, elements []ast.Expression) ast.Expression {
+	parameters := []*ast.Identifier{}
+
+	for _, element := range elements {
+		parameter, ok := element.(*ast.Identifier)
+		if !ok {
+			parser.addError(diagnostic.INVALID_ARROW_PARAMETER, tok, "invalid parameter %s of arrow function", element.String()).
+				WithHint("parameters of arrow functions have to be identifiers")
+			return nil
+		}
+
+		parameters = append(parameters, parameter)
+	}
+
+	return parser.parseArrowFunction(tok, parameters)
+}
+
+// parseArrowFunction parses the body of `x => body` or `(a, b) => body` with
+// the peek token being the arrow. The result is a regular function literal
+// whose body consists of the body expression.
+func (parser *Parser) parseArrowFunction(tok token.Token, parameters []*ast.Identifier) ast.Expression {
+	parser.nextToken()
+	arrow := parser.currentToken
+
+	parser.nextToken()
+	inGenerator, inFunction := parser.inGenerator, parser.inFunction
+	parser.inGenerator, parser.inFunction = false, true
+	body := parser.parseExpression(LOWEST)
+	parser.inGenerator, parser.inFunction = inGenerator, inFunction
+
+	// The literal starts with the parameters.
+	fn := tok
+	fn.Type, fn.Literal = token.FUNCTION, "fn"
+
+	return &ast.FunctionLiteral{
+		Token:      fn,
+		Parameters: parameters,
+		Body: &ast.BlockStatement{
+			Token:      arrow,
+			Statements: []ast.Statement{&ast.ExpressionStatement{Token: arrow, Expression: body}},
+		},
+	}
 }
 
 func (parser *Parser) parseIfStatement() ast.Expression {
@@ -286,19 +638,34 @@ func (parser *Parser) parseBlockStatement() *ast.BlockStatement {
 		parser.nextToken()
 	}
 
+	if parser.currentTokenIs(token.RIGHT_CURLY_BRACE) {
+		block.EndToken = parser.currentToken
+	}
+
 	return block
 }
 
-func (parser *Parser) noPrefixParseFnError(token token.Token) {
-	message := fmt.Sprintf("no prefix parse function for %s at %d:%d found", token.Type, token.Line, token.Column)
-	parser.errors = append(parser.errors, message)
+func (parser *Parser) noPrefixParseFnError(tok token.Token) {
+	if tok.Type == token.ILLEGAL {
+		// Illegal characters are reported by the lexer.
+		parser.panicking = true
+		return
+	}
+
+	parser.addError(diagnostic.MISSING_EXPRESSION, tok, "no prefix parse function for %s found", tok.Type)
 }
 
 func (parser *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{
+	identifier := &ast.Identifier{
 		Token: parser.currentToken,
 		Value: parser.currentToken.Literal,
 	}
+
+	if parser.peekTokenIs(token.ARROW) {
+		return parser.parseArrowFunction(identifier.Token, []*ast.Identifier{identifier})
+	}
+
+	return identifier
 }
 
 func (parser *Parser) parseIntegerLiteral() ast.Expression {
@@ -307,8 +674,8 @@ func (parser *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(parser.currentToken.Literal, 10, 64)
 	if err != nil {
-		message := fmt.Sprintf("Could not parse %q as integer at %d:%d", parser.currentToken.Literal, parser.currentToken.Line, parser.currentToken.Column)
-		parser.errors = append(parser.errors, message)
+		parser.addError(diagnostic.INVALID_INTEGER, parser.currentToken, "could not parse %q as integer", parser.currentToken.Literal).
+			WithHint("integers have to fit into 64 bits")
 		return nil
 	}
 
@@ -329,17 +696,30 @@ func (parser *Parser) parseFunctionLiteral() ast.Expression {
 		Token: parser.currentToken,
 	}
 
+	if parser.peekTokenIs(token.ASTERISK) {
+		parser.nextToken()
+		function.IsGenerator = true
+	}
+
 	if !parser.expectPeek(token.LEFT_PAREN) {
 		return nil
 	}
 
 	function.Parameters = parser.parseFunctionParameters()
+	if function.Parameters == nil {
+		return nil
+	}
 
 	if !parser.expectPeek(token.LEFT_CURLY_BRACE) {
 		return nil
 	}
 
+	// Only the body of the generator itself may yield, not the bodies of
+	// functions nested in it.
+	inGenerator, inFunction := parser.inGenerator, parser.inFunction
+	parser.inGenerator, parser.inFunction = function.IsGenerator, true
 	function.Body = parser.parseBlockStatement()
+	parser.inGenerator, parser.inFunction = inGenerator, inFunction
 
 	return function
 }
@@ -352,7 +732,9 @@ func (parser *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 
-	parser.nextToken()
+	if !parser.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
 
 	identifiers = append(identifiers, &ast.Identifier{
 		Token: parser.currentToken,
@@ -361,7 +743,10 @@ func (parser *Parser) parseFunctionParameters() []*ast.Identifier {
 
 	for parser.peekTokenIs(token.COMMA) {
 		parser.nextToken()
-		parser.nextToken()
+		if !parser.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+
 		identifiers = append(identifiers, &ast.Identifier{
 			Token: parser.currentToken,
 			Value: parser.currentToken.Literal,
@@ -407,7 +792,47 @@ func (parser *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 func (parser *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	call := &ast.CallExpression{Function: function}
 	call.Arguments = parser.parseExpressionList(token.RIGHT_PAREN)
-	return call
+	call.EndToken = parser.currentToken
+	return parser.parsePartialApplication(call)
+}
+
+// parsePartialApplication turns a call with `_` placeholder arguments into a
+// function literal taking one parameter per placeholder: `add(1, _)` becomes
+// `fn(_1) { add(1, _1) }`. As in Scala the function and the other arguments
+// are evaluated whenever the resulting function is called.
+func (parser *Parser) parsePartialApplication(call *ast.CallExpression) ast.Expression {
+	parameters := []*ast.Identifier{}
+
+	for i, argument := range call.Arguments {
+		placeholder, ok := argument.(*ast.Identifier)
+		if !ok || placeholder.Value != "_" {
+			continue
+		}
+
+		// Identifiers cannot contain digits, so the names cannot clash with
+		// identifiers of the program.
+		name := fmt.Sprintf("_%d", len(parameters)+1)
+		parameter := &ast.Identifier{Token: placeholder.Token, Value: name}
+		parameter.Token.Literal = name
+
+		parameters = append(parameters, parameter)
+		call.Arguments[i] = parameter
+	}
+
+	if len(parameters) == 0 {
+		return call
+	}
+
+	pos := call.Pos()
+
+	return &ast.FunctionLiteral{
+		Token:      token.Token{Type: token.FUNCTION, Literal: "fn", Line: pos.Line, Column: pos.Column, File: pos.File, Offset: pos.Offset},
+		Parameters: parameters,
+		Body: &ast.BlockStatement{
+			Token:      parser.currentToken,
+			Statements: []ast.Statement{&ast.ExpressionStatement{Token: parser.currentToken, Expression: call}},
+		},
+	}
 }
 
 func (parser *Parser) parseBoolean() ast.Expression {
@@ -418,12 +843,74 @@ func (parser *Parser) parseBoolean() ast.Expression {
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
-	arr := &ast.ArrayLiteral{Token: p.currentToken}
-	arr.Elements = p.parseExpressionList(token.RIGHT_SQUARE_BRACKET)
+	arr := &ast.ArrayLiteral{
+		Token:    p.currentToken,
+		Elements: []ast.Expression{},
+	}
+
+	if p.peekTokenIs(token.RIGHT_SQUARE_BRACKET) {
+		p.nextToken()
+		arr.EndToken = p.currentToken
+		return arr
+	}
+
+	p.nextToken()
+	first := p.parseExpression(LOWEST)
+
+	// A for after the first element turns the literal into a comprehension.
+	if p.peekTokenIs(token.FOR) {
+		comprehension := &ast.ArrayComprehension{Token: arr.Token, Element: first}
+
+		comprehension.Clause = p.parseComprehensionClause()
+		if comprehension.Clause == nil || !p.expectPeek(token.RIGHT_SQUARE_BRACKET) {
+			return nil
+		}
+		comprehension.EndToken = p.currentToken
+
+		return comprehension
+	}
+
+	arr.Elements = append(arr.Elements, first)
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		arr.Elements = append(arr.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(token.RIGHT_SQUARE_BRACKET) {
+		return nil
+	}
+	arr.EndToken = p.currentToken
 
 	return arr
 }
 
+// parseComprehensionClause parses `for x in xs if condition` with the peek
+// token being the for.
+func (p *Parser) parseComprehensionClause() *ast.ComprehensionClause {
+	p.nextToken()
+
+	clause := &ast.ComprehensionClause{Token: p.currentToken}
+
+	var ok bool
+	clause.Identifier, clause.Names, ok = p.parseLoopTargets()
+	if !ok {
+		return nil
+	}
+
+	p.nextToken()
+	clause.Iterable = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		clause.Condition = p.parseExpression(LOWEST)
+	}
+
+	return clause
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
@@ -455,25 +942,73 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	}
 
 	p.nextToken()
+
+	if p.currentTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, nil)
+	}
+
 	exp.Index = p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		return p.parseSliceExpression(exp.Token, left, exp.Index)
+	}
+
 	if !p.expectPeek(token.RIGHT_SQUARE_BRACKET) {
 		return nil
 	}
+	exp.EndToken = p.currentToken
 
 	return exp
 }
 
+// parseSliceExpression parses the rest of `left[start:end]` with the current
+// token being the colon.
+func (p *Parser) parseSliceExpression(tok token.Token, left ast.Expression, start ast.Expression) ast.Expression {
+	slice := &ast.SliceExpression{
+		Token: tok,
+		Left:  left,
+		Start: start,
+	}
+
+	if p.peekTokenIs(token.RIGHT_SQUARE_BRACKET) {
+		p.nextToken()
+		slice.EndToken = p.currentToken
+		return slice
+	}
+
+	p.nextToken()
+	slice.Stop = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RIGHT_SQUARE_BRACKET) {
+		return nil
+	}
+	slice.EndToken = p.currentToken
+
+	return slice
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{
 		Token: p.currentToken,
-		Pairs: make(map[ast.Expression]ast.Expression),
+		Pairs: []ast.HashLiteralPair{},
 	}
 
-	for !p.peekTokenIs(token.RIGHT_CURLY_BRACE) {
+	if p.peekTokenIs(token.RIGHT_CURLY_BRACE) {
 		p.nextToken()
-		key := p.parseExpression(LOWEST)
+		hash.EndToken = p.currentToken
+		return hash
+	}
+
+	p.nextToken()
+	key := p.parseExpression(LOWEST)
 
+	// Without a colon after the first element the literal is a set: `{1, 2}`.
+	if !p.peekTokenIs(token.COLON) {
+		return p.parseSetLiteral(hash.Token, key)
+	}
+
+	for {
 		if !p.expectPeek(token.COLON) {
 			return nil
 		}
@@ -481,33 +1016,76 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		if len(hash.Pairs) == 0 && p.peekTokenIs(token.FOR) {
+			comprehension := &ast.HashComprehension{Token: hash.Token, Key: key, Value: value}
+
+			comprehension.Clause = p.parseComprehensionClause()
+			if comprehension.Clause == nil || !p.expectPeek(token.RIGHT_CURLY_BRACE) {
+				return nil
+			}
+			comprehension.EndToken = p.currentToken
+
+			return comprehension
+		}
+
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.RIGHT_CURLY_BRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
+
+		if p.peekTokenIs(token.RIGHT_CURLY_BRACE) {
+			break
+		}
+
+		p.nextToken()
+		key = p.parseExpression(LOWEST)
 	}
 
 	if !p.expectPeek(token.RIGHT_CURLY_BRACE) {
 		return nil
 	}
+	hash.EndToken = p.currentToken
 
 	return hash
 }
 
+func (p *Parser) parseSetLiteral(tok token.Token, first ast.Expression) ast.Expression {
+	set := &ast.SetLiteral{
+		Token:    tok,
+		Elements: []ast.Expression{first},
+	}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.RIGHT_CURLY_BRACE) {
+			break
+		}
+
+		p.nextToken()
+		set.Elements = append(set.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(token.RIGHT_CURLY_BRACE) {
+		return nil
+	}
+	set.EndToken = p.currentToken
+
+	return set
+}
+
 func (p *Parser) parsePropertyExpression(left ast.Expression) ast.Expression {
 	property := &ast.PropertyExpression{
 		Token:   p.currentToken,
 		Subject: left,
 	}
 
-	if !p.peekTokenIs(token.IDENTIFIER) {
+	if !p.expectPeek(token.IDENTIFIER) {
 		return nil
 	}
 
-	p.nextToken()
-	name := p.parseIdentifier().(*ast.Identifier)
-	property.Name = name
+	property.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 
 	return property
 }
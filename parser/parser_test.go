@@ -3,7 +3,9 @@ package parser
 import (
 	"fmt"
 	"github.com/hendrikbursian/monkey-programming-language/ast"
+	"github.com/hendrikbursian/monkey-programming-language/diagnostic"
 	"github.com/hendrikbursian/monkey-programming-language/lexer"
+	"reflect"
 	"testing"
 )
 
@@ -415,6 +417,26 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"xs |> filter(f) |> map(g)",
+			"((xs |> filter(f)) |> map(g))",
+		},
+		{
+			"a == b |> f",
+			"((a == b) |> f)",
+		},
+		{
+			"1 + 2 |> f",
+			"((1 + 2) |> f)",
+		},
+		{
+			"xs |> f >> g << h",
+			"(xs |> ((f >> g) << h))",
+		},
+		{
+			"f >> g == h",
+			"(f >> (g == h))",
+		},
 	}
 
 	for _, test := range tests {
@@ -899,14 +921,14 @@ func TestHashLiteral(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not a stringLiteral. got=%T", key)
+			t.Errorf("key is not a stringLiteral. got=%T", pair.Key)
 		}
 		expectedValue := expected[literal.Value]
 
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expectedValue)
 	}
 }
 
@@ -938,10 +960,10 @@ func TestHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
@@ -951,7 +973,21 @@ func TestHashLiteralsWithExpressions(t *testing.T) {
 			continue
 		}
 
-		testFunc(value)
+		testFunc(pair.Value)
+	}
+}
+
+func TestHashLiteralKeepsSourceOrder(t *testing.T) {
+	input := `{"c": 1, "a": 2 * 2, b: 3}`
+
+	for i := 0; i < 10; i++ {
+		p, program := testParse(input)
+		checkParserErrors(t, p)
+
+		expected := `{"c": 1, "a": (2 * 2), b: 3}`
+		if program.String() != expected {
+			t.Fatalf("program.String() wrong. want=%q, got=%q", expected, program.String())
+		}
 	}
 }
 
@@ -993,3 +1029,477 @@ func TestProperty(t *testing.T) {
 	}
 
 }
+
+func TestTupleLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"()", "()"},
+		{"(1,)", "(1,)"},
+		{"(1)", "1"},
+		{"(1, 2 * 3)", "(1, (2 * 3))"},
+		{"(1, (2, 3), a,)", "(1, (2, 3), a)"},
+		{"f((a, b))", "f((a, b))"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, program := testParse(tt.input)
+			checkParserErrors(t, p)
+
+			if program.String() != tt.expected {
+				t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+			}
+		})
+	}
+}
+
+func TestDestructuringLetStatement(t *testing.T) {
+	input := `let (q, r) = divmod(7, 2);`
+
+	p, program := testParse(input)
+	checkParserErrors(t, p)
+
+	statement, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+
+	if statement.Identifier != nil {
+		t.Errorf("statement.Identifier is not nil. got=%q", statement.Identifier)
+	}
+
+	if len(statement.Names) != 2 {
+		t.Fatalf("statement.Names has wrong length. want=%d, got=%d", 2, len(statement.Names))
+	}
+
+	testIdentifier(t, statement.Names[0], "q")
+	testIdentifier(t, statement.Names[1], "r")
+
+	if statement.String() != "let (q, r) = divmod(7, 2);" {
+		t.Errorf("statement.String() wrong. got=%q", statement.String())
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for x in xs { puts(x) }", "for x in xs { puts(x) }"},
+		{"for (k, v) in pairs(h) { k }", "for (k, v) in pairs(h) { k }"},
+		{"for x in 1..3 { x }", "for x in (1 .. 3) { x }"},
+		{"for x in xs { x };", "for x in xs { x }"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, program := testParse(tt.input)
+			checkParserErrors(t, p)
+
+			if len(program.Statements) != 1 {
+				t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+			}
+
+			if _, ok := program.Statements[0].(*ast.ForStatement); !ok {
+				t.Fatalf("statement is not *ast.ForStatement. got=%T", program.Statements[0])
+			}
+
+			if program.String() != tt.expected {
+				t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+			}
+		})
+	}
+}
+
+func TestGeneratorFunctions(t *testing.T) {
+	input := `fn*(n) { for i in 0..n { yield i * i; } }`
+
+	p, program := testParse(input)
+	checkParserErrors(t, p)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	function, ok := statement.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("statement.Expression is not *ast.FunctionLiteral. got=%T", statement.Expression)
+	}
+
+	if !function.IsGenerator {
+		t.Errorf("function.IsGenerator is not true")
+	}
+
+	if function.String() != "fn*(n) { for i in (0 .. n) { yield (i * i); } }" {
+		t.Errorf("function.String() wrong. got=%q", function.String())
+	}
+}
+
+func TestYieldOutsideOfGenerator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"yield 1;", "yield outside of generator function at 1:1"},
+		{"fn() { yield 1; }", "yield outside of generator function at 1:8"},
+		{"fn*() { fn() { yield 1; } }", "yield outside of generator function at 1:16"},
+		{"fn*() { x => if (true) { yield x } }", "yield outside of generator function at 1:26"},
+		{"fn*() { yield x => if (true) { yield x } }", "yield outside of generator function at 1:32"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, _ := testParse(tt.input)
+
+			if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+				t.Errorf("wrong parser errors. want=%q, got=%q", tt.expected, p.Errors())
+			}
+
+			if p.Diagnostics()[0].Code != diagnostic.YIELD_OUTSIDE_GENERATOR {
+				t.Errorf("wrong diagnostic code. got=%q", p.Diagnostics()[0].Code)
+			}
+		})
+	}
+}
+
+func TestDeferStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { defer close(f); 1 }", "fn() { defer close(f);1 }"},
+		{"fn() { if x { defer puts(1) } }", "fn() { if x { defer puts(1); } }"},
+		{"x => if x { defer puts(x); x }", "fn(x) { if x { defer puts(x);x } }"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, program := testParse(tt.input)
+			checkParserErrors(t, p)
+
+			if program.String() != tt.expected {
+				t.Errorf("wrong program. want=%q, got=%q", tt.expected, program.String())
+			}
+		})
+	}
+}
+
+func TestDeferOutsideOfFunction(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"defer puts(1);", "defer outside of function at 1:1"},
+		{"if true { defer puts(1); }", "defer outside of function at 1:11"},
+		{"for x in [1] { defer puts(x); }", "defer outside of function at 1:16"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, _ := testParse(tt.input)
+
+			if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+				t.Errorf("wrong parser errors. want=%q, got=%q", tt.expected, p.Errors())
+			}
+
+			if p.Diagnostics()[0].Code != diagnostic.DEFER_OUTSIDE_FUNCTION {
+				t.Errorf("wrong diagnostic code. got=%q", p.Diagnostics()[0].Code)
+			}
+		})
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []diagnostic.Diagnostic
+	}{
+		{"let = 5;", []diagnostic.Diagnostic{
+			{Code: diagnostic.UNEXPECTED_TOKEN, Message: "expected next token to be 'IDENTIFIER', got '=' instead", Start: diagnostic.Position{Offset: 4, Line: 1, Column: 5}, End: diagnostic.Position{Offset: 5, Line: 1, Column: 6}},
+		}},
+		{"99999999999999999999", []diagnostic.Diagnostic{
+			{Code: diagnostic.INVALID_INTEGER, Message: "could not parse \"99999999999999999999\" as integer", Start: diagnostic.Position{Offset: 0, Line: 1, Column: 1}, End: diagnostic.Position{Offset: 20, Line: 1, Column: 21}, Hints: []string{"integers have to fit into 64 bits"}},
+		}},
+		{"1 + 2;\n3 ? 4", []diagnostic.Diagnostic{
+			{Code: diagnostic.ILLEGAL_CHARACTER, Message: "illegal character \"?\"", Start: diagnostic.Position{Offset: 9, Line: 2, Column: 3}, End: diagnostic.Position{Offset: 10, Line: 2, Column: 4}},
+		}},
+		{"let s = \"abc", []diagnostic.Diagnostic{
+			{Code: diagnostic.UNTERMINATED_STRING, Message: "unterminated string", Start: diagnostic.Position{Offset: 8, Line: 1, Column: 9}, End: diagnostic.Position{Offset: 12, Line: 1, Column: 13}, Hints: []string{"add a closing '\"'"}},
+		}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, _ := testParse(tt.input)
+
+			diagnostics := p.Diagnostics()
+			if len(diagnostics) != len(tt.expected) {
+				t.Fatalf("wrong number of diagnostics. want=%d, got=%d (%q)", len(tt.expected), len(diagnostics), p.Errors())
+			}
+
+			for j, expected := range tt.expected {
+				if !reflect.DeepEqual(*diagnostics[j], expected) {
+					t.Errorf("wrong diagnostic %d. want=%+v, got=%+v", j, expected, *diagnostics[j])
+				}
+			}
+		})
+	}
+}
+
+func TestComprehensions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[x * 2 for x in xs]", "[(x * 2) for x in xs]"},
+		{"[x * 2 for x in xs if x > 0]", "[(x * 2) for x in xs if (x > 0)]"},
+		{"[(a, b) for (a, b) in zip(xs, ys)]", "[(a, b) for (a, b) in zip(xs, ys)]"},
+		{"{k: v for (k, v) in pairs(h)}", "{k: v for (k, v) in pairs(h)}"},
+		{"{x: x * x for x in 1..3 if x != 2}", "{x: (x * x) for x in (1 .. 3) if (x != 2)}"},
+		{"[[y for y in x] for x in xs]", "[[y for y in x] for x in xs]"},
+		{"[]", "[]"},
+		{"[1, 2]", "[1, 2]"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, program := testParse(tt.input)
+			checkParserErrors(t, p)
+
+			if program.String() != tt.expected {
+				t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+			}
+		})
+	}
+}
+
+func TestArrowFunctionsAndPlaceholders(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x => x * 2", "fn(x) { (x * 2) }"},
+		{"(a, b) => a + b", "fn(a,b) { (a + b) }"},
+		{"(x) => x", "fn(x) { x }"},
+		{"() => 1", "fn() { 1 }"},
+		{"x => y => x + y", "fn(x) { fn(y) { (x + y) } }"},
+		{"map(xs, x => x * 2)", "map(xs, fn(x) { (x * 2) })"},
+		{"let double = x => x * 2;", "let double = fn(x) { (x * 2) };"},
+		{"add(1, _)", "fn(_1) { add(1, _1) }"},
+		{"f(_, 2, _)", "fn(_1,_2) { f(_1, 2, _2) }"},
+		{"xs |> map(_, f)", "(xs |> fn(_1) { map(_1, f) })"},
+		{"(a, b)", "(a, b)"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, program := testParse(tt.input)
+			checkParserErrors(t, p)
+
+			if program.String() != tt.expected {
+				t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+			}
+		})
+	}
+}
+
+func TestInvalidArrowFunctionParameters(t *testing.T) {
+	p, _ := testParse("(a, 1) => a")
+
+	expected := "invalid parameter 1 of arrow function at 1:1"
+	if len(p.Errors()) == 0 || p.Errors()[0] != expected {
+		t.Errorf("wrong parser errors. want=%q, got=%q", expected, p.Errors())
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		errors   []string
+		expected string
+	}{
+		{
+			"let = 5; let y = 2; y",
+			[]string{"expected next token to be 'IDENTIFIER', got '=' instead at 1:5"},
+			"<bad statement>let y = 2;y",
+		},
+		{
+			"let x = ) + 5; let y = 1",
+			[]string{"no prefix parse function for ) found at 1:9"},
+			"let x = <bad expression>;let y = 1;",
+		},
+		{
+			"let f = fn() { let = 1; 2 }; f()",
+			[]string{"expected next token to be 'IDENTIFIER', got '=' instead at 1:20"},
+			"let f = fn() { <bad statement>2 };f()",
+		},
+		{
+			"if (x) { let = 1 } else { 2 } x",
+			[]string{"expected next token to be 'IDENTIFIER', got '=' instead at 1:14"},
+			"if x { <bad statement> } else { 2 }x",
+		},
+		{
+			"let x = (1, 2; let y = [1, 2; return y",
+			[]string{"expected next token to be ')', got ';' instead at 1:14", "expected next token to be ']', got ';' instead at 1:29"},
+			"let x = <bad expression>;let y = <bad expression>;return y;",
+		},
+		{
+			"a.; b",
+			[]string{"expected next token to be 'IDENTIFIER', got ';' instead at 1:3"},
+			"<bad expression>b",
+		},
+		{
+			"fn(a, 1) { a }; 5",
+			[]string{"expected next token to be 'IDENTIFIER', got 'INTEGER' instead at 1:7"},
+			"<bad expression>5",
+		},
+		{
+			"let x = ) { a } 5",
+			[]string{"no prefix parse function for ) found at 1:9"},
+			"let x = <bad expression>;",
+		},
+		{
+			`let h = {"a": 1, "b" 2}; h`,
+			[]string{"expected next token to be ':', got 'INTEGER' instead at 1:22"},
+			"let h = <bad expression>;h",
+		},
+		{
+			"{k: v for }; 1",
+			[]string{"expected next token to be 'IDENTIFIER', got '}' instead at 1:11"},
+			"<bad expression>1",
+		},
+		{
+			"fn*() { yield ; }; 1",
+			[]string{"no prefix parse function for ; found at 1:15"},
+			"fn*() { yield <bad expression>; }1",
+		},
+		{
+			`let f = fn() { let h = {"a": 1 2}; 3 }; f()`,
+			[]string{"expected next token to be ',', got 'INTEGER' instead at 1:32"},
+			"let f = fn() { let h = <bad expression>;3 };f()",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, program := testParse(tt.input)
+
+			if !reflect.DeepEqual(p.Errors(), tt.errors) {
+				t.Errorf("wrong parser errors. want=%q, got=%q", tt.errors, p.Errors())
+			}
+
+			if program.String() != tt.expected {
+				t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+			}
+		})
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1 + foo(2, 3);", "let x = 1 + foo(2, 3)"},
+		{"  a.b[1:2] ", "a.b[1:2]"},
+		{"xs[0]", "xs[0]"},
+		{"-(1, 2)", "-(1, 2)"},
+		{"if (x) { 1 } else { \"two\" }", "if (x) { 1 } else { \"two\" }"},
+		{"fn(a) { a }", "fn(a) { a }"},
+		{"(a, b) => a + b", "(a, b) => a + b"},
+		{"x => x", "x => x"},
+		{"add(1, _)", "add(1, _)"},
+		{"[x for x in xs if x]", "[x for x in xs if x]"},
+		{"{k: v for (k, v) in pairs(h)}", "{k: v for (k, v) in pairs(h)}"},
+		{"{1, 2}", "{1, 2}"},
+		{"{\"a\": 1}", "{\"a\": 1}"},
+		{"for x in xs { puts(x) }", "for x in xs { puts(x) }"},
+		{"return 1;", "return 1"},
+		{"let = 5; 1", "let = 5;"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			_, program := testParse(tt.input)
+			statement := program.Statements[0]
+
+			if source := tt.input[statement.Pos().Offset:statement.End().Offset]; source != tt.expected {
+				t.Errorf("wrong span. want=%q, got=%q", tt.expected, source)
+			}
+		})
+	}
+}
+
+func TestFileNames(t *testing.T) {
+	program := New(lexer.NewFile("main.mk", "\nlet x = 1;")).ParseProgram()
+
+	pos := program.Statements[0].Pos()
+	if pos.String() != "main.mk:2:1" {
+		t.Errorf("wrong position. got=%s", pos)
+	}
+}
+
+func TestSetLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{1}", "{1}"},
+		{"{1, 2 + 3,}", "{1, (2 + 3)}"},
+		{`{"a": 1}`, `{"a": 1}`},
+		{"a in b == true", "((a in b) == true)"},
+		{"a | b & c", "(a | (b & c))"},
+		{"a & b - c", "(a & (b - c))"},
+		{"x in a | b", "(x in (a | b))"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, program := testParse(tt.input)
+			checkParserErrors(t, p)
+
+			if program.String() != tt.expected {
+				t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+			}
+		})
+	}
+
+	p, program := testParse("{1, 2}")
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	set, ok := stmt.Expression.(*ast.SetLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.SetLiteral. got=%T", stmt.Expression)
+	}
+
+	testIntegerLiteral(t, set.Elements[0], 1)
+	testIntegerLiteral(t, set.Elements[1], 2)
+}
+
+func TestSliceAndRangeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:3]", "(a[1:3])"},
+		{"a[:-1]", "(a[:(-1)])"},
+		{"a[2:]", "(a[2:])"},
+		{"a[:]", "(a[:])"},
+		{"a[1 + 1:l(a) - 1]", "(a[(1 + 1):(l(a) - 1)])"},
+		{"1..10", "(1 .. 10)"},
+		{"1..<n + 1", "(1 ..< (n + 1))"},
+		{"x in 1..10", "(x in (1 .. 10))"},
+		{"(1..10)[2:]", "((1 .. 10)[2:])"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			p, program := testParse(tt.input)
+			checkParserErrors(t, p)
+
+			if program.String() != tt.expected {
+				t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package repl
 import (
 	"bufio"
 	"fmt"
+	"github.com/hendrikbursian/monkey-programming-language/diagnostic"
 	"github.com/hendrikbursian/monkey-programming-language/evaluator"
 	"github.com/hendrikbursian/monkey-programming-language/lexer"
 	"github.com/hendrikbursian/monkey-programming-language/object"
@@ -13,9 +14,24 @@ import (
 
 const PROMPT = ">>> "
 
-func Start(in io.Reader, out io.Writer) {
+type Options struct {
+	// MaxCallDepth limits the depth of nested function calls, see
+	// evaluator.DEFAULT_MAX_CALL_DEPTH.
+	MaxCallDepth int
+
+	// ConditionMode decides whether conditions accept values that are not
+	// booleans.
+	ConditionMode object.ConditionMode
+
+	// Color enables ANSI colors in error messages.
+	Color bool
+}
+
+func Start(in io.Reader, out io.Writer, options Options) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	env.SetMaxCallDepth(options.MaxCallDepth)
+	env.SetConditionMode(options.ConditionMode)
 
 	for {
 		fmt.Printf(PROMPT)
@@ -31,19 +47,23 @@ func Start(in io.Reader, out io.Writer) {
 			os.Exit(0)
 		}
 
-		lexer := lexer.New(line)
-		parser := parser.New(lexer)
+		p := parser.New(lexer.New(line))
+
+		program := p.ParseProgram()
 
-		program := parser.ParseProgram()
+		renderer := &diagnostic.Renderer{Source: line, Color: options.Color}
 
-		if len(parser.Errors()) != 0 {
-			printParserErrors(out, parser.Errors())
+		if len(p.Errors()) != 0 {
+			printParserErrors(out, renderer, p.Diagnostics())
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
+		evaluated := evaluator.EvalSafely(program, env)
+		if err, ok := evaluated.(*object.Error); ok {
+			renderer.Render(out, err.Inspect(), lexer.TokenSpan(line, err.Line, err.Column))
+			io.WriteString(out, err.Traceback())
+		} else if evaluated.Type() != object.UNIT_OBJECT {
+			io.WriteString(out, object.Pretty(evaluated, evaluator.PRETTY_WIDTH))
 			io.WriteString(out, "\n")
 		}
 	}
@@ -63,11 +83,11 @@ const MONKEY_FACE = `
            '-----'
 `
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, renderer *diagnostic.Renderer, diagnostics []*diagnostic.Diagnostic) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Whoops! We ran into some monkey business here!\n")
 	io.WriteString(out, " parser errros:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, d := range diagnostics {
+		renderer.RenderDiagnostic(out, d)
 	}
 }
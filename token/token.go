@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 type Token struct {
@@ -7,6 +9,43 @@ type Token struct {
 	Literal string
 	Line    int
 	Column  int
+
+	// File names the source the token was read from. Offset is the byte
+	// offset of its first character.
+	File   string
+	Offset int
+
+	// EndLine, EndColumn and EndOffset point just past its last character.
+	EndLine   int
+	EndColumn int
+	EndOffset int
+}
+
+// Position is a location in a source file. Offset is a byte offset starting
+// at 0, Line and Column start at 1.
+type Position struct {
+	File   string
+	Offset int
+	Line   int
+	Column int
+}
+
+func (position Position) String() string {
+	if position.File == "" {
+		return fmt.Sprintf("%d:%d", position.Line, position.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", position.File, position.Line, position.Column)
+}
+
+// Pos returns the position of the first character of the token.
+func (tok Token) Pos() Position {
+	return Position{File: tok.File, Offset: tok.Offset, Line: tok.Line, Column: tok.Column}
+}
+
+// End returns the position just past the last character of the token.
+func (tok Token) End() Position {
+	return Position{File: tok.File, Offset: tok.EndOffset, Line: tok.EndLine, Column: tok.EndColumn}
 }
 
 const (
@@ -29,12 +68,21 @@ const (
 	BANG         = "!"
 	EQUAL        = "=="
 	NOT_EQUAL    = "!="
+	BAR          = "|"
+	AMPERSAND    = "&"
+
+	ARROW         = "=>"
+	PIPE          = "|>"
+	COMPOSE_RIGHT = ">>"
+	COMPOSE_LEFT  = "<<"
 
 	// Delimiters
 	COMMA                = ","
 	SEMICOLON            = ";"
 	COLON                = ":"
 	DOT                  = "."
+	RANGE                = ".."
+	RANGE_EXCLUSIVE      = "..<"
 	LEFT_PAREN           = "("
 	RIGHT_PAREN          = ")"
 	LEFT_CURLY_BRACE     = "{"
@@ -45,21 +93,31 @@ const (
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	RETURN   = "RETURN"
+	IN       = "IN"
+	FOR      = "FOR"
+	YIELD    = "YIELD"
+	DEFER    = "DEFER"
 )
 
 var keywords = map[string]TokenType{
 	"fn":     FUNCTION,
 	"let":    LET,
+	"const":  CONST,
 	"if":     IF,
 	"else":   ELSE,
 	"true":   TRUE,
 	"false":  FALSE,
 	"return": RETURN,
+	"in":     IN,
+	"for":    FOR,
+	"yield":  YIELD,
+	"defer":  DEFER,
 }
 
 func GetTokenType(identifier string) TokenType {